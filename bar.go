@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/scheduler"
+	"github.com/grentenrg/go-bar/widgets"
+)
+
+type Bar struct {
+	ctx        context.Context
+	res        resources
+	window     *gtk.Window
+	box        *gtk.Box
+	css        *gtk.CssProvider
+	sections   []*section // in the order of config.SectionNames
+	scheduler  *scheduler.Scheduler
+	events     *libs.EventBus
+	compositor compositor.Compositor
+
+	mu sync.Mutex // guards the entries of all sections
+}
+
+// section is one of the left, center and right areas of the bar.
+type section struct {
+	box     *gtk.Box
+	padding uint
+	entries []*entry
+}
+
+func NewBar(ctx context.Context, res resources) *Bar {
+	dispatch := func(f func()) {
+		glib.IdleAdd(f)
+	}
+
+	bar := &Bar{
+		ctx:       ctx,
+		res:       res,
+		scheduler: scheduler.New(dispatch),
+		events:    libs.NewEventBus(ctx, dispatch),
+	}
+	bar.compositor = bar.newCompositor(dispatch)
+	bar.window = bar.createWindow()
+	return bar
+}
+
+// newCompositor returns the backend for the compositor the bar runs under.
+// Without a supported one, compositor widgets stay empty.
+func (b *Bar) newCompositor(dispatch func(func())) compositor.Compositor {
+	detected := compositor.Detect(b.ctx)
+
+	switch detected.Kind {
+	case compositor.KindNiri:
+		return compositor.NewNiri(b.ctx, detected.Socket, dispatch)
+	case compositor.KindSway:
+		return compositor.NewSway(b.ctx, detected.Socket, dispatch)
+	case compositor.KindHyprland:
+		hyprctl := libs.NewHyprctl()
+		state := libs.NewHyprlandState(b.ctx, b.events, hyprctl)
+		return compositor.NewHyprland(b.events, state, hyprctl)
+	default:
+		libs.Log.Println("no supported compositor found, compositor widgets stay empty")
+		return compositor.NewNone(dispatch)
+	}
+}
+
+// output returns the name of the output showing the bar, or "" while it is
+// unknown. GTK does not name monitors, so the monitor of the window is
+// matched with the compositor outputs by position.
+func (b *Bar) output() string {
+	win, err := b.window.GetWindow()
+	if err != nil || win == nil {
+		return ""
+	}
+
+	display, err := gdk.DisplayGetDefault()
+	if err != nil {
+		return ""
+	}
+
+	monitor, err := display.GetMonitorAtWindow(win)
+	if err != nil || monitor == nil {
+		return ""
+	}
+
+	geometry := monitor.GetGeometry()
+	if o := b.compositor.State().OutputAt(geometry.GetX(), geometry.GetY()); o != nil {
+		return o.Name
+	}
+
+	return ""
+}
+
+func (b *Bar) createWindow() *gtk.Window {
+	win, err := gtk.WindowNew(gtk.WINDOW_TOPLEVEL)
+	if err != nil {
+		log.Fatal("Unable to create window:", err)
+	}
+
+	// Set window properties
+	win.SetTitle("Hyprland Status Bar")
+
+	width, height := getScreenDimensions()
+	win.SetDefaultSize(width, height)
+
+	return win
+}
+
+// loadStyle loads the stylesheet and swaps it in for the current one. On
+// error the current stylesheet stays in place.
+func (b *Bar) loadStyle() error {
+	css, err := b.res.loadStyle()
+	if err != nil {
+		return fmt.Errorf("unable to read CSS file: %w", err)
+	}
+
+	return b.applyStyle(css)
+}
+
+// applyStyle swaps css in for the current stylesheet.
+func (b *Bar) applyStyle(css string) error {
+	cssProvider, err := gtk.CssProviderNew()
+	if err != nil {
+		return fmt.Errorf("unable to create CSS provider: %w", err)
+	}
+
+	if err := cssProvider.LoadFromData(css); err != nil {
+		return fmt.Errorf("unable to load CSS: %w", err)
+	}
+
+	screen, err := gdk.ScreenGetDefault()
+	if err != nil {
+		return fmt.Errorf("unable to get default screen: %w", err)
+	}
+
+	if b.css != nil {
+		gtk.RemoveProviderForScreen(screen, b.css)
+	}
+	gtk.AddProviderForScreen(screen, cssProvider, gtk.STYLE_PROVIDER_PRIORITY_APPLICATION)
+	b.css = cssProvider
+
+	return nil
+}
+
+func (b *Bar) setPosition() {
+	DockTop(b.window)
+}
+
+func (b *Bar) createMainBox() {
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 5)
+	if err != nil {
+		log.Fatal("Unable to create box:", err)
+	}
+
+	styleContext, err := box.GetStyleContext()
+	if err != nil {
+		log.Fatal("Unable to get style context:", err)
+	}
+
+	styleContext.AddClass("bar")
+
+	b.window.Add(box)
+	b.box = box
+}
+
+// createSections adds the left, center and right sections to the main box.
+func (b *Bar) createSections() {
+	leftBox, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 5)
+	if err != nil {
+		log.Fatal("Unable to create left box:", err)
+	}
+	leftBox.SetHExpand(true)
+	leftBox.SetHAlign(gtk.ALIGN_START)
+
+	centerBox, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		log.Fatal("Unable to create center box:", err)
+	}
+	centerBox.SetHExpand(true)
+	centerBox.SetHAlign(gtk.ALIGN_CENTER)
+
+	rightBox, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 5)
+	if err != nil {
+		log.Fatal("Unable to create right box:", err)
+	}
+	rightBox.SetHExpand(true)
+	rightBox.SetHAlign(gtk.ALIGN_END)
+
+	// Add all sections to the main box
+	b.box.PackStart(leftBox, true, true, 0)
+	b.box.PackStart(centerBox, true, true, 0)
+	b.box.PackStart(rightBox, true, true, 0)
+
+	b.sections = []*section{
+		{box: leftBox, padding: 5},
+		{box: centerBox, padding: 0},
+		{box: rightBox, padding: 5},
+	}
+}
+
+// applyConfig brings the sections of the bar in line with cfg. Widgets whose
+// entry did not change keep running, the others are created and started or
+// destroyed. If the configuration names a widget that cannot be built, the
+// bar is left untouched.
+func (b *Bar) applyConfig(cfg *config.Config) error {
+	layouts := [][]config.WidgetConfig{cfg.Left, cfg.Center, cfg.Right}
+
+	type plan struct {
+		entries []*entry
+		kept    []bool // indexed like the current entries of the section
+	}
+	plans := make([]plan, len(b.sections))
+
+	var created []*entry
+	for i, s := range b.sections {
+		p := plan{kept: make([]bool, len(s.entries))}
+
+		for _, wc := range layouts[i] {
+			if j := s.find(wc, p.kept); j >= 0 {
+				p.kept[j] = true
+				p.entries = append(p.entries, s.entries[j])
+				continue
+			}
+
+			widget, err := widgets.New(wc)
+			if err != nil {
+				return err
+			}
+
+			e := newEntry(wc, widget, s)
+			created = append(created, e)
+			p.entries = append(p.entries, e)
+		}
+
+		plans[i] = p
+	}
+
+	b.mu.Lock()
+	var removed []*entry
+	for i, s := range b.sections {
+		for j, e := range s.entries {
+			if !plans[i].kept[j] {
+				removed = append(removed, e)
+			}
+		}
+		s.entries = plans[i].entries
+	}
+	b.mu.Unlock()
+
+	for _, e := range removed {
+		e.destroy()
+	}
+
+	for _, e := range created {
+		b.activate(e)
+	}
+
+	for _, s := range b.sections {
+		s.arrange(created)
+	}
+
+	return nil
+}
+
+// find returns the index of the first entry that is not yet kept and was
+// built from the same configuration as wc, or -1.
+func (s *section) find(wc config.WidgetConfig, kept []bool) int {
+	for i, e := range s.entries {
+		if !kept[i] && e.config.Equal(wc) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// arrange packs the newly created entries of the section into its box and
+// puts every entry in configuration order.
+func (s *section) arrange(created []*entry) {
+	for i, e := range s.entries {
+		for _, c := range created {
+			if c == e {
+				s.attach(e)
+			}
+		}
+
+		s.box.ReorderChild(e.box(), i)
+	}
+
+	s.box.ShowAll()
+}
+
+// attach packs the box of an entry into the section box.
+func (s *section) attach(e *entry) {
+	s.box.PackStart(e.box(), false, false, s.padding)
+
+	styleContext, err := e.box().GetStyleContext()
+	if err != nil {
+		log.Fatal("Unable to get style context:", err)
+	}
+
+	styleContext.AddClass(e.widget.Name())
+	if e.class != "" {
+		styleContext.AddClass(e.class)
+	}
+}
+
+// replace swaps the placeholder of an entry for the box of its widget once
+// the widget is running.
+func (s *section) replace(e *entry, placeholder *gtk.Box) {
+	placeholder.Destroy()
+	s.attach(e)
+
+	for i, other := range s.entries {
+		if other == e {
+			s.box.ReorderChild(e.box(), i)
+		}
+	}
+
+	e.box().ShowAll()
+}
+
+// destroyWidgets removes every entry from the bar: pending activations are
+// cancelled and running widgets stopped and destroyed.
+func (b *Bar) destroyWidgets() {
+	b.mu.Lock()
+	var entries []*entry
+	for _, s := range b.sections {
+		entries = append(entries, s.entries...)
+		s.entries = nil
+	}
+	b.mu.Unlock()
+
+	for _, e := range entries {
+		e.destroy()
+	}
+}
+
+// watch reloads the stylesheet and the configuration whenever they change
+// on disk. Reload errors are reported and the previous state keeps running.
+func (b *Bar) watch() {
+	configs, styles := b.res.watched()
+
+	isStyle := make(map[string]bool)
+	for _, path := range styles {
+		isStyle[path] = true
+	}
+
+	err := libs.WatchFiles(b.ctx, append(configs, styles...), func(path string) {
+		glib.IdleAdd(func() {
+			if isStyle[path] {
+				if err := b.loadStyle(); err != nil {
+					libs.Log.Println("keeping previous stylesheet:", err)
+				}
+				return
+			}
+
+			if err := b.reloadConfig(); err != nil {
+				libs.Log.Println("keeping previous configuration:", err)
+			}
+		})
+	})
+	if err != nil {
+		libs.Log.Println("unable to watch for changes:", err)
+	}
+}
+
+// reloadConfig parses the configuration again and applies it.
+func (b *Bar) reloadConfig() error {
+	cfg, err := b.res.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return b.applyConfig(cfg)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/ipc"
+	"github.com/grentenrg/go-bar/widgets"
+)
+
+// command is a go-bar subcommand. run receives the arguments following the
+// command name and returns the exit status.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "[--config FILE] [--style FILE]", "start the bar (default)", runCommand},
+		{"check-config", "[--config FILE] [--style FILE]", "validate the configuration and the stylesheet", checkConfigCommand},
+		{"list-widgets", "", "list the widget types and their options", listWidgetsCommand},
+		{"msg", "COMMAND [ARGS...]", "send a command to the running bar", msgCommand},
+	}
+}
+
+// dispatch runs the subcommand named by the first argument. Without one, or
+// when the first argument is a flag, the bar is started so that plain
+// "go-bar --config FILE" keeps working.
+func dispatch(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage(os.Stdout)
+			return 0
+		}
+	}
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runCommand(args)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "go-bar: unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-bar [COMMAND] [ARGS...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "go-bar COMMAND -h" for the arguments of a command.`)
+}
+
+// newFlagSet returns the flag set of a subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("go-bar "+name, flag.ContinueOnError)
+	flags.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(flags.Output(), "Usage: go-bar %s %s\n\n%s\n", name, cmd.usage, cmd.summary)
+			}
+		}
+		if name == "msg" {
+			fmt.Fprintf(flags.Output(), "\nCommands: %s\n", controlUsage())
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// resourceFlags registers --config and --style on flags.
+func resourceFlags(flags *flag.FlagSet, res *resources) {
+	flags.StringVar(&res.configPath, "config", "", "path of the configuration file (default: search $XDG_CONFIG_HOME/go-bar and $XDG_CONFIG_DIRS)")
+	flags.StringVar(&res.stylePath, "style", "", "path of the stylesheet (default: search like --config, then the built-in theme)")
+}
+
+// parseFlags parses args and returns the exit status to use when the command
+// must not continue.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, false
+		}
+		return 2, false
+	}
+
+	return 0, true
+}
+
+func runCommand(args []string) int {
+	var res resources
+	flags := newFlagSet("run")
+	resourceFlags(flags, &res)
+	if status, ok := parseFlags(flags, args); !ok {
+		return status
+	}
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	return run(res)
+}
+
+// checkConfigCommand loads the configuration and the stylesheet the way the
+// bar would, and builds every configured widget without showing it.
+func checkConfigCommand(args []string) int {
+	var res resources
+	flags := newFlagSet("check-config")
+	resourceFlags(flags, &res)
+	if status, ok := parseFlags(flags, args); !ok {
+		return status
+	}
+
+	failed := false
+	fail := func(what string, err error) {
+		fmt.Fprintf(os.Stderr, "go-bar: invalid %s: %v\n", what, err)
+		failed = true
+	}
+
+	if cfg, err := res.loadConfig(); err != nil {
+		fail("configuration", err)
+	} else {
+		// Sections are checked from left to right so that the output is
+		// the same on every run
+		sections := cfg.Sections()
+		for _, name := range config.SectionNames {
+			for _, wc := range sections[name] {
+				// Widgets only touch GTK in Create, so building them
+				// validates their options without a display
+				if _, err := widgets.New(wc); err != nil {
+					fail("configuration", err)
+				}
+			}
+		}
+	}
+
+	if err := checkStyle(res); err != nil {
+		fail("stylesheet", err)
+	}
+
+	if failed {
+		return 1
+	}
+
+	fmt.Println("configuration OK")
+	return 0
+}
+
+// checkStyle parses the stylesheet with GTK.
+func checkStyle(res resources) error {
+	css, err := res.loadStyle()
+	if err != nil {
+		return err
+	}
+
+	// CSS parsing does not need a display, so a failure to open one (e.g.
+	// in CI) is not an error here
+	gtk.InitCheck(nil)
+
+	provider, err := gtk.CssProviderNew()
+	if err != nil {
+		return fmt.Errorf("unable to create CSS provider: %w", err)
+	}
+
+	return provider.LoadFromData(css)
+}
+
+func listWidgetsCommand(args []string) int {
+	flags := newFlagSet("list-widgets")
+	if status, ok := parseFlags(flags, args); !ok {
+		return status
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	for i, name := range widgets.Types() {
+		options, err := widgets.Describe(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go-bar: %s: %v\n", name, err)
+			return 1
+		}
+
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintln(tw, name)
+
+		if len(options) == 0 {
+			fmt.Fprintln(tw, "  (no options)")
+		}
+		for _, option := range options {
+			fmt.Fprintf(tw, "  %s\t%s\tdefault %s\n", option.Key, option.Kind, option.Default)
+		}
+	}
+
+	return 0
+}
+
+// msgCommand sends its arguments to the running bar and prints the answer.
+func msgCommand(args []string) int {
+	flags := newFlagSet("msg")
+	timeout := flags.Duration("timeout", 5*time.Second, "how long to wait for an answer")
+	if status, ok := parseFlags(flags, args); !ok {
+		return status
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	resp, err := ipc.Send(ctx, ipc.Request{Command: flags.Arg(0), Args: flags.Args()[1:]})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go-bar:", err)
+		return 1
+	}
+
+	if !resp.OK {
+		fmt.Fprintln(os.Stderr, "go-bar:", resp.Error)
+		return 1
+	}
+
+	if len(resp.Data) > 0 {
+		var out bytes.Buffer
+		if err := json.Indent(&out, resp.Data, "", "  "); err != nil {
+			fmt.Println(string(resp.Data))
+		} else {
+			fmt.Println(out.String())
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,166 @@
+// Package compositor abstracts the compositor the bar runs under, so that
+// the workspace and window widgets work the same on every supported one.
+//
+// Each backend mirrors the outputs, workspaces and windows of its
+// compositor and tells watchers about changes. Like the rest of the bar,
+// the mirrored state is only read on the GTK main thread, and watchers are
+// called there.
+package compositor
+
+import (
+	"context"
+	"strconv"
+)
+
+// Output is a monitor. Outputs are identified by name everywhere; the ID is
+// only used to resolve references given by users, see State.Output.
+type Output struct {
+	ID      int
+	Name    string
+	Focused bool
+	// X and Y are the position of the output in the layout, in logical
+	// pixels.
+	X, Y int
+	// ActiveWorkspace is the name of the workspace shown on the output.
+	ActiveWorkspace string
+}
+
+// Workspace is a workspace of an output.
+type Workspace struct {
+	// ID is the number of the workspace as the compositor shows it, used to
+	// sort workspaces.
+	ID     int
+	Name   string
+	Output string
+	// Focused is set for the workspace shown on the focused output.
+	Focused bool
+	// Visible is set for the workspaces shown on any output.
+	Visible bool
+	Urgent  bool
+}
+
+// Window is a toplevel window.
+type Window struct {
+	// ID identifies the window for the compositor, e.g. its address on
+	// Hyprland.
+	ID string
+	// AppID is the Wayland app ID or the X11 class of the window.
+	AppID string
+	Title string
+	// Workspace and Output are the names of the workspace and the output
+	// of the window.
+	Workspace string
+	Output    string
+	Focused   bool
+	Urgent    bool
+}
+
+// State is a snapshot of the compositor.
+type State struct {
+	Outputs    []Output
+	Workspaces []Workspace
+	Windows    []Window
+}
+
+// FocusedOutput returns the output that has focus, or nil if unknown.
+func (s State) FocusedOutput() *Output {
+	for i := range s.Outputs {
+		if s.Outputs[i].Focused {
+			return &s.Outputs[i]
+		}
+	}
+
+	return nil
+}
+
+// Output returns the output named ref, or with ref as its ID, or nil if
+// there is none.
+func (s State) Output(ref string) *Output {
+	for i := range s.Outputs {
+		if s.Outputs[i].Name == ref {
+			return &s.Outputs[i]
+		}
+	}
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		for i := range s.Outputs {
+			if s.Outputs[i].ID == id {
+				return &s.Outputs[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// OutputAt returns the output positioned at x, y, or nil if there is none.
+func (s State) OutputAt(x, y int) *Output {
+	for i := range s.Outputs {
+		if s.Outputs[i].X == x && s.Outputs[i].Y == y {
+			return &s.Outputs[i]
+		}
+	}
+
+	return nil
+}
+
+// FocusedWorkspace returns the workspace shown on the focused output, or
+// nil if unknown.
+func (s State) FocusedWorkspace() *Workspace {
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Focused {
+			return &s.Workspaces[i]
+		}
+	}
+
+	return nil
+}
+
+// FocusedWindow returns the window that has focus, or nil if none has.
+func (s State) FocusedWindow() *Window {
+	for i := range s.Windows {
+		if s.Windows[i].Focused {
+			return &s.Windows[i]
+		}
+	}
+
+	return nil
+}
+
+// Exists reports whether the compositor has ws, by ID if it has one and by
+// name otherwise.
+func (s State) Exists(ws Workspace) bool {
+	for _, other := range s.Workspaces {
+		if ws.ID > 0 && other.ID == ws.ID || ws.ID <= 0 && other.Name == ws.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Watcher describes what a watcher of a Compositor wants to hear about.
+type Watcher struct {
+	// Changed is called on the main thread when the state changed, and
+	// right away if the state is already known.
+	Changed func()
+	// Report, if set, is called on the main thread when the compositor
+	// cannot be followed, and with nil once it can again.
+	Report func(err error)
+}
+
+// Compositor is a backend for a compositor.
+type Compositor interface {
+	// Name identifies the compositor, e.g. "hyprland".
+	Name() string
+	// Watch registers w and returns a function unregistering it. Both must
+	// be called on the main thread; no callback runs after the returned
+	// function.
+	Watch(w Watcher) (stop func())
+	// State returns the current state. It must be called on the main
+	// thread.
+	State() State
+	// SwitchWorkspace focuses a workspace. Workspaces the compositor does
+	// not have yet are created where it allows, on ws.Output if set.
+	SwitchWorkspace(ctx context.Context, ws Workspace) error
+}
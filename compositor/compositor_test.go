@@ -0,0 +1,160 @@
+package compositor
+
+import (
+	"testing"
+	"time"
+)
+
+// mainThread runs the functions dispatched by a backend one at a time on
+// a goroutine of its own, like the GTK main loop does.
+type mainThread struct {
+	funcs chan func()
+}
+
+func newMainThread(t *testing.T) *mainThread {
+	m := &mainThread{funcs: make(chan func(), 1024)}
+
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case f := <-m.funcs:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return m
+}
+
+// dispatch queues f, as backends expect from their dispatch function.
+func (m *mainThread) dispatch(f func()) {
+	m.funcs <- f
+}
+
+// run calls f on the main thread and waits for it to return.
+func (m *mainThread) run(f func()) {
+	done := make(chan struct{})
+	m.funcs <- func() {
+		f()
+		close(done)
+	}
+	<-done
+}
+
+// waitState waits until the state of c satisfies cond and returns it. It
+// fails the test if that takes more than five seconds.
+func waitState(t *testing.T, m *mainThread, c Compositor, what string, cond func(State) bool) State {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var state State
+		ok := false
+		m.run(func() {
+			state = c.State()
+			ok = cond(state)
+		})
+		if ok {
+			return state
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s, state is %+v", what, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// watch registers a watcher on the main thread and returns the errors it
+// is reported, and a function stopping it.
+func watch(m *mainThread, c Compositor) (reports chan error, stop func()) {
+	reports = make(chan error, 100)
+
+	var unwatch func()
+	m.run(func() {
+		unwatch = c.Watch(Watcher{
+			Changed: func() {},
+			Report: func(err error) {
+				reports <- err
+			},
+		})
+	})
+
+	return reports, func() {
+		m.run(unwatch)
+	}
+}
+
+// workspace returns the workspace named name, or nil.
+func (s State) workspace(name string) *Workspace {
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Name == name {
+			return &s.Workspaces[i]
+		}
+	}
+
+	return nil
+}
+
+func TestStateOutput(t *testing.T) {
+	state := State{Outputs: []Output{
+		{ID: 0, Name: "DP-1"},
+		{ID: 1, Name: "HDMI-A-1"},
+		{ID: 2, Name: "1"},
+	}}
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"DP-1", "DP-1"},
+		{"HDMI-A-1", "HDMI-A-1"},
+		{"0", "DP-1"},
+		// Names win over IDs
+		{"1", "1"},
+		{"2", "1"},
+		{"DP-2", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if o := state.Output(tt.ref); o != nil {
+			got = o.Name
+		}
+
+		if got != tt.want {
+			t.Errorf("Output(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestStateExists(t *testing.T) {
+	state := State{Workspaces: []Workspace{
+		{ID: 2, Name: "2:web", Output: "DP-1"},
+		{ID: -1, Name: "chat", Output: "DP-1"},
+	}}
+
+	tests := []struct {
+		ws   Workspace
+		want bool
+	}{
+		{Workspace{ID: 2, Name: "2"}, true},
+		{Workspace{ID: 3, Name: "3"}, false},
+		{Workspace{Name: "chat"}, true},
+		{Workspace{Name: "mail"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := state.Exists(tt.ws); got != tt.want {
+			t.Errorf("Exists(%+v) = %v, want %v", tt.ws, got, tt.want)
+		}
+	}
+}
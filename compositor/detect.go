@@ -0,0 +1,74 @@
+package compositor
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Kinds of compositors Detect can find.
+const (
+	KindNone     = "none"
+	KindHyprland = "hyprland"
+	KindSway     = "sway"
+	KindNiri     = "niri"
+)
+
+// Detection is the compositor the bar runs under.
+type Detection struct {
+	Kind string
+	// Socket is the IPC socket of sway and niri.
+	Socket string
+}
+
+// Detect finds the compositor the bar runs under. Compositors whose socket
+// answers come first, in the order niri, sway, Hyprland, so that a nested
+// session wins over the one it runs in. Failing that, a compositor named
+// by the environment is picked even though it does not answer yet, as its
+// backend keeps retrying. KindNone is returned when nothing is found.
+func Detect(ctx context.Context) Detection {
+	candidates := []Detection{
+		{Kind: KindNiri, Socket: NiriSocket()},
+		{Kind: KindSway, Socket: SwaySocket()},
+	}
+
+	for _, d := range candidates {
+		if d.Socket != "" && socketAlive(ctx, d.Socket) {
+			return d
+		}
+	}
+
+	if libs.HyprlandRunning(ctx) {
+		return Detection{Kind: KindHyprland}
+	}
+
+	for _, d := range candidates {
+		if d.Socket != "" {
+			return d
+		}
+	}
+
+	if os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "" {
+		return Detection{Kind: KindHyprland}
+	}
+
+	return Detection{Kind: KindNone}
+}
+
+// socketAlive reports whether the socket at path accepts connections.
+func socketAlive(ctx context.Context, path string) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+	return true
+}
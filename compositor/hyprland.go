@@ -0,0 +1,210 @@
+package compositor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Hyprland follows Hyprland through the shared Hyprland state. As Hyprland
+// does not keep track of urgency, windows are remembered as urgent from
+// their urgent event until their workspace is focused.
+type Hyprland struct {
+	bus     *libs.EventBus
+	state   *libs.HyprlandState
+	hyprctl *libs.Hyprctl
+
+	// Accessed on the main thread only
+	watchers map[*Watcher]bool
+	events   *libs.Subscription
+	urgent   map[string]urgentWindow // keyed by address
+}
+
+// urgentWindow is a window remembered as urgent.
+type urgentWindow struct {
+	// seen is set once the window was found in the clients. Until then,
+	// clientLists is the number of client lists applied before its urgent
+	// event. The first list after it may come from a query sent earlier,
+	// so the window is forgotten if the second one does not have it either.
+	seen        bool
+	clientLists uint64
+}
+
+func NewHyprland(bus *libs.EventBus, state *libs.HyprlandState, hyprctl *libs.Hyprctl) *Hyprland {
+	return &Hyprland{
+		bus:      bus,
+		state:    state,
+		hyprctl:  hyprctl,
+		watchers: make(map[*Watcher]bool),
+		urgent:   make(map[string]urgentWindow),
+	}
+}
+
+func (h *Hyprland) Name() string {
+	return KindHyprland
+}
+
+func (h *Hyprland) Watch(w Watcher) func() {
+	watcher := &w
+	h.watchers[watcher] = true
+
+	if h.events == nil {
+		h.events = h.bus.Subscribe(libs.Subscriber{
+			Events: []string{"urgent"},
+			Handle: h.handleUrgent,
+		})
+	}
+
+	watch := h.state.Watch(libs.Watcher{
+		Slices: libs.StateAll,
+		Changed: func() {
+			h.pruneUrgent()
+			w.Changed()
+		},
+		Report: w.Report,
+	})
+
+	return func() {
+		watch.Stop()
+		delete(h.watchers, watcher)
+
+		if len(h.watchers) == 0 {
+			h.events.Unsubscribe()
+			h.events = nil
+		}
+	}
+}
+
+// handleUrgent remembers the window of an urgent event, unless its
+// workspace is focused already.
+func (h *Hyprland) handleUrgent(ev libs.Event) {
+	decoded, err := ev.Decode()
+	if err != nil {
+		libs.Log.Println("unable to decode urgent event:", err)
+		return
+	}
+
+	urgent, ok := decoded.(libs.UrgentEvent)
+	if !ok {
+		return
+	}
+
+	// Windows not in the clients yet are resolved once they are
+	client, seen := h.state.Client(urgent.Address)
+	if seen && client.Workspace.ID == h.state.ActiveWorkspace().ID {
+		return
+	}
+	h.urgent[urgent.Address] = urgentWindow{seen: seen, clientLists: h.state.ClientLists()}
+
+	for w := range h.watchers {
+		w.Changed()
+	}
+}
+
+// pruneUrgent forgets urgent windows that were closed, that never showed
+// up in the clients queried after their urgent event, or whose workspace
+// is focused.
+func (h *Hyprland) pruneUrgent() {
+	active := h.state.ActiveWorkspace()
+	clientLists := h.state.ClientLists()
+
+	for address, urgent := range h.urgent {
+		client, ok := h.state.Client(address)
+		switch {
+		case !ok && (urgent.seen || clientLists > urgent.clientLists+1):
+			delete(h.urgent, address)
+		case ok && client.Workspace.ID == active.ID:
+			delete(h.urgent, address)
+		case ok:
+			h.urgent[address] = urgentWindow{seen: true}
+		}
+	}
+}
+
+func (h *Hyprland) State() State {
+	var state State
+
+	focused := h.state.ActiveWorkspace()
+	visible := make(map[int]bool)
+
+	for _, m := range h.state.Monitors() {
+		state.Outputs = append(state.Outputs, Output{
+			ID:              m.ID,
+			Name:            m.Name,
+			Focused:         m.Focused,
+			X:               m.X,
+			Y:               m.Y,
+			ActiveWorkspace: m.ActiveWorkspace.Name,
+		})
+		visible[m.ActiveWorkspace.ID] = true
+	}
+
+	urgentWorkspaces := make(map[int]bool)
+	for address := range h.urgent {
+		if c, ok := h.state.Client(address); ok {
+			urgentWorkspaces[c.Workspace.ID] = true
+		}
+	}
+
+	outputs := make(map[int]string)
+	for _, ws := range h.state.Workspaces() {
+		outputs[ws.ID] = ws.Monitor
+		state.Workspaces = append(state.Workspaces, Workspace{
+			ID:      ws.ID,
+			Name:    ws.Name,
+			Output:  ws.Monitor,
+			Focused: ws.ID == focused.ID,
+			Visible: visible[ws.ID],
+			Urgent:  urgentWorkspaces[ws.ID],
+		})
+	}
+
+	var active string
+	if c := h.state.ActiveWindow(); c != nil {
+		active = c.Address
+	}
+
+	for _, c := range h.state.Clients() {
+		_, urgent := h.urgent[c.Address]
+		state.Windows = append(state.Windows, Window{
+			ID:        c.Address,
+			AppID:     c.Class,
+			Title:     c.Title,
+			Workspace: c.Workspace.Name,
+			Output:    outputs[c.Workspace.ID],
+			Focused:   c.Address == active,
+			Urgent:    urgent,
+		})
+	}
+
+	return state
+}
+
+func (h *Hyprland) SwitchWorkspace(ctx context.Context, ws Workspace) error {
+	// Named workspaces have negative IDs
+	target := strconv.Itoa(ws.ID)
+	if ws.ID <= 0 {
+		target = "name:" + ws.Name
+	}
+
+	if ws.Output == "" || h.State().Exists(ws) {
+		return h.hyprctl.Dispatch(ctx, "workspace", target)
+	}
+
+	// New workspaces open on the focused monitor
+	replies, err := h.hyprctl.Batch(ctx, "dispatch focusmonitor "+ws.Output, "dispatch workspace "+target)
+	if err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		if reply = strings.TrimSpace(reply); reply != "ok" {
+			return fmt.Errorf("unable to create workspace %s: %s", ws.Name, reply)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package compositor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/libs/hyprtest"
+)
+
+// newTestHyprland starts a backend following srv and waits for its first
+// state.
+func newTestHyprland(t *testing.T, srv *hyprtest.Server) (*Hyprland, *mainThread) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	m := newMainThread(t)
+	bus := libs.NewEventBus(ctx, m.dispatch)
+	hyprctl := libs.NewHyprctl()
+
+	var h *Hyprland
+	m.run(func() {
+		h = NewHyprland(bus, libs.NewHyprlandState(ctx, bus, hyprctl), hyprctl)
+	})
+
+	_, stop := watch(m, h)
+	t.Cleanup(stop)
+
+	srv.WaitListeners(1)
+	waitState(t, m, h, "the first state", func(s State) bool {
+		return len(s.Outputs) > 0
+	})
+
+	return h, m
+}
+
+// serveHyprland sets up srv with workspaces 1 and 2 on DP-1, where 1 has
+// focus, and the named workspace "web" on HDMI-A-1.
+func serveHyprland(srv *hyprtest.Server) {
+	srv.SetJSON("monitors", []libs.Monitor{
+		{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: libs.WorkspaceRef{ID: 1, Name: "1"}},
+		{ID: 1, Name: "HDMI-A-1", X: 1920, ActiveWorkspace: libs.WorkspaceRef{ID: -1337, Name: "web"}},
+	})
+	srv.SetJSON("workspaces", []libs.Workspace{
+		{ID: 1, Name: "1", Monitor: "DP-1", Windows: 1},
+		{ID: 2, Name: "2", Monitor: "DP-1", Windows: 1},
+		{ID: -1337, Name: "web", Monitor: "HDMI-A-1", Windows: 1},
+	})
+	srv.SetJSON("clients", []libs.Client{
+		{Address: "0xa", Class: "kitty", Title: "shell", Workspace: libs.WorkspaceRef{ID: 1, Name: "1"}},
+		{Address: "0xb", Class: "mpv", Title: "video", Workspace: libs.WorkspaceRef{ID: 2, Name: "2"}},
+		{Address: "0xc", Class: "firefox", Title: "news", Workspace: libs.WorkspaceRef{ID: -1337, Name: "web"}},
+	})
+	srv.SetJSON("activewindow", libs.Client{Address: "0xa"})
+}
+
+func TestHyprlandState(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	serveHyprland(srv)
+
+	h, m := newTestHyprland(t, srv)
+
+	var got State
+	m.run(func() {
+		got = h.State()
+	})
+
+	want := State{
+		Outputs: []Output{
+			{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: "1"},
+			{ID: 1, Name: "HDMI-A-1", X: 1920, ActiveWorkspace: "web"},
+		},
+		Workspaces: []Workspace{
+			{ID: 1, Name: "1", Output: "DP-1", Focused: true, Visible: true},
+			{ID: 2, Name: "2", Output: "DP-1"},
+			{ID: -1337, Name: "web", Output: "HDMI-A-1", Visible: true},
+		},
+		Windows: []Window{
+			{ID: "0xa", AppID: "kitty", Title: "shell", Workspace: "1", Output: "DP-1", Focused: true},
+			{ID: "0xb", AppID: "mpv", Title: "video", Workspace: "2", Output: "DP-1"},
+			{ID: "0xc", AppID: "firefox", Title: "news", Workspace: "web", Output: "HDMI-A-1"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("State() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestHyprlandEvents(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	serveHyprland(srv)
+
+	h, m := newTestHyprland(t, srv)
+
+	srv.Emit("activewindowv2", "c")
+	waitState(t, m, h, "the focus to move to firefox", func(s State) bool {
+		w := s.FocusedWindow()
+		return w != nil && w.AppID == "firefox"
+	})
+
+	srv.Emit("windowtitlev2", "c,Hello, World")
+	waitState(t, m, h, "the new title", func(s State) bool {
+		w := s.FocusedWindow()
+		return w != nil && w.Title == "Hello, World"
+	})
+
+	srv.Emit("workspacev2", "2,2")
+	waitState(t, m, h, "workspace 2 to get focus", func(s State) bool {
+		ws := s.FocusedWorkspace()
+		return ws != nil && ws.Name == "2"
+	})
+}
+
+func TestHyprlandUrgent(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	serveHyprland(srv)
+
+	h, m := newTestHyprland(t, srv)
+
+	// Windows on the focused workspace never become urgent
+	srv.Emit("urgent", "a")
+	srv.Emit("urgent", "b")
+	state := waitState(t, m, h, "workspace 2 to become urgent", func(s State) bool {
+		ws := s.workspace("2")
+		return ws != nil && ws.Urgent
+	})
+	if state.workspace("1").Urgent {
+		t.Error("focused workspace 1 became urgent")
+	}
+
+	// Focusing the workspace of the window clears its urgency, even after
+	// the focus moves away again
+	srv.SetJSON("monitors", []libs.Monitor{
+		{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: libs.WorkspaceRef{ID: 2, Name: "2"}},
+		{ID: 1, Name: "HDMI-A-1", X: 1920, ActiveWorkspace: libs.WorkspaceRef{ID: -1337, Name: "web"}},
+	})
+	srv.Emit("workspacev2", "2,2")
+	waitState(t, m, h, "workspace 2 to get focus", func(s State) bool {
+		return s.FocusedWorkspace().Name == "2"
+	})
+
+	srv.Emit("workspacev2", "1,1")
+	state = waitState(t, m, h, "workspace 1 to get focus", func(s State) bool {
+		return s.FocusedWorkspace().Name == "1"
+	})
+	if state.workspace("2").Urgent {
+		t.Error("workspace 2 still urgent after it was visited")
+	}
+}
+
+// waitClientLists emits openwindow events until the backend applied n more
+// client lists, and returns the urgent windows it remembers afterwards.
+func waitClientLists(t *testing.T, srv *hyprtest.Server, m *mainThread, h *Hyprland, n uint64) map[string]urgentWindow {
+	t.Helper()
+
+	var start uint64
+	m.run(func() {
+		start = h.state.ClientLists()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var lists uint64
+		urgent := make(map[string]urgentWindow)
+		m.run(func() {
+			lists = h.state.ClientLists()
+			for address, u := range h.urgent {
+				urgent[address] = u
+			}
+		})
+		if lists >= start+n {
+			return urgent
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d client lists, got %d", n, lists-start)
+		}
+		srv.Emit("openwindow", "e,2,kitty,other")
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHyprlandUrgentUnseen(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	serveHyprland(srv)
+
+	h, m := newTestHyprland(t, srv)
+
+	// A window that closed before the clients were queried again
+	srv.Emit("urgent", "d")
+	waitClientLists(t, srv, m, h, 1)
+
+	// Changing the clients lets the backend prune its urgent windows
+	srv.SetJSON("clients", []libs.Client{
+		{Address: "0xa", Class: "kitty", Title: "shell", Workspace: libs.WorkspaceRef{ID: 1, Name: "1"}},
+		{Address: "0xb", Class: "mpv", Title: "video", Workspace: libs.WorkspaceRef{ID: 2, Name: "2"}},
+	})
+	if urgent := waitClientLists(t, srv, m, h, 2); len(urgent) != 0 {
+		t.Errorf("urgent windows = %v, want none once the clients were queried twice", urgent)
+	}
+
+	// A window that shows up in the clients after its urgent event
+	srv.Emit("urgent", "f")
+	srv.SetJSON("clients", []libs.Client{
+		{Address: "0xa", Class: "kitty", Title: "shell", Workspace: libs.WorkspaceRef{ID: 1, Name: "1"}},
+		{Address: "0xf", Class: "mpv", Title: "video", Workspace: libs.WorkspaceRef{ID: 2, Name: "2"}},
+	})
+	srv.Emit("openwindow", "f,2,mpv,video")
+	waitState(t, m, h, "workspace 2 to become urgent", func(s State) bool {
+		ws := s.workspace("2")
+		return ws != nil && ws.Urgent
+	})
+
+	if urgent := waitClientLists(t, srv, m, h, 2); !urgent["0xf"].seen {
+		t.Errorf("urgent windows = %v, want 0xf seen", urgent)
+	}
+}
+
+func TestHyprlandSwitchWorkspace(t *testing.T) {
+	tests := []struct {
+		name string
+		ws   Workspace
+		want []string
+	}{
+		{
+			name: "existing",
+			ws:   Workspace{ID: 2, Name: "2", Output: "DP-1"},
+			want: []string{"workspace 2"},
+		},
+		{
+			name: "named",
+			ws:   Workspace{ID: -1337, Name: "web", Output: "HDMI-A-1"},
+			want: []string{"workspace name:web"},
+		},
+		{
+			name: "new on an output",
+			ws:   Workspace{ID: 3, Name: "3", Output: "HDMI-A-1"},
+			want: []string{"focusmonitor HDMI-A-1", "workspace 3"},
+		},
+		{
+			name: "new named on an output",
+			ws:   Workspace{Name: "mail", Output: "HDMI-A-1"},
+			want: []string{"focusmonitor HDMI-A-1", "workspace name:mail"},
+		},
+		{
+			name: "new on the focused output",
+			ws:   Workspace{ID: 3, Name: "3"},
+			want: []string{"workspace 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := hyprtest.NewServer(t)
+			serveHyprland(srv)
+
+			h, m := newTestHyprland(t, srv)
+
+			var err error
+			m.run(func() {
+				err = h.SwitchWorkspace(context.Background(), tt.ws)
+			})
+			if err != nil {
+				t.Fatalf("SwitchWorkspace() error = %v", err)
+			}
+
+			if got := srv.Dispatches(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dispatched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package compositor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+)
+
+// i3ipcMagic starts every message of the i3 IPC protocol, which sway
+// implements too.
+const i3ipcMagic = "i3-ipc"
+
+// Message types of the i3 IPC protocol.
+const (
+	i3ipcRunCommand    uint32 = 0
+	i3ipcGetWorkspaces uint32 = 1
+	i3ipcSubscribe     uint32 = 2
+	i3ipcGetOutputs    uint32 = 3
+	i3ipcGetTree       uint32 = 4
+
+	// i3ipcEvent is set in the type of event messages.
+	i3ipcEvent uint32 = 1 << 31
+)
+
+// i3ipcConn is a connection to an i3 IPC socket. Messages are the magic
+// string followed by the payload length and the message type, both in
+// native byte order, and the JSON payload.
+type i3ipcConn struct {
+	conn net.Conn
+}
+
+func dialI3ipc(ctx context.Context, path string) (*i3ipcConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IPC socket: %w", err)
+	}
+
+	return &i3ipcConn{conn: conn}, nil
+}
+
+func (c *i3ipcConn) Close() error {
+	return c.conn.Close()
+}
+
+// send writes a message.
+func (c *i3ipcConn) send(typ uint32, payload []byte) error {
+	header := make([]byte, len(i3ipcMagic)+8)
+	copy(header, i3ipcMagic)
+	binary.NativeEndian.PutUint32(header[len(i3ipcMagic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(header[len(i3ipcMagic)+4:], typ)
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("unable to send IPC message: %w", err)
+	}
+
+	return nil
+}
+
+// receive reads a message.
+func (c *i3ipcConn) receive() (uint32, []byte, error) {
+	header := make([]byte, len(i3ipcMagic)+8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return 0, nil, fmt.Errorf("unable to read IPC message: %w", err)
+	}
+
+	if !bytes.Equal(header[:len(i3ipcMagic)], []byte(i3ipcMagic)) {
+		return 0, nil, fmt.Errorf("invalid IPC message header %q", header)
+	}
+
+	length := binary.NativeEndian.Uint32(header[len(i3ipcMagic):])
+	typ := binary.NativeEndian.Uint32(header[len(i3ipcMagic)+4:])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return 0, nil, fmt.Errorf("unable to read IPC message: %w", err)
+	}
+
+	return typ, payload, nil
+}
+
+// request sends a message and decodes the reply into v. Events received in
+// between are skipped.
+func (c *i3ipcConn) request(typ uint32, payload []byte, v any) error {
+	if err := c.send(typ, payload); err != nil {
+		return err
+	}
+
+	for {
+		replyType, reply, err := c.receive()
+		if err != nil {
+			return err
+		}
+
+		if replyType&i3ipcEvent != 0 {
+			continue
+		}
+
+		if replyType != typ {
+			return fmt.Errorf("expected IPC reply of type %d, got %d", typ, replyType)
+		}
+
+		if err := json.Unmarshal(reply, v); err != nil {
+			return fmt.Errorf("unable to parse IPC reply: %w", err)
+		}
+
+		return nil
+	}
+}
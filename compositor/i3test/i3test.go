@@ -0,0 +1,319 @@
+// Package i3test provides a fake sway or i3 instance for tests of code
+// talking to the i3 IPC protocol, so that it can run without a compositor.
+//
+// A Server listens on a temporary socket and points $SWAYSOCK at it:
+//
+//	srv := i3test.NewServer(t)
+//	srv.SetJSON(i3test.GetWorkspaces, []map[string]any{{"num": 1, "name": "1"}})
+//	// ... start the code under test, then
+//	srv.WaitSubscribers(1)
+//	srv.Emit("workspace", map[string]any{"change": "focus"})
+//	// ... and check what it ran
+//	srv.Commands() // e.g. [`workspace "2"`]
+package i3test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const magic = "i3-ipc"
+
+// Message types of the i3 IPC protocol.
+const (
+	RunCommand    uint32 = 0
+	GetWorkspaces uint32 = 1
+	Subscribe     uint32 = 2
+	GetOutputs    uint32 = 3
+	GetTree       uint32 = 4
+)
+
+// eventTypes maps event names to their message types.
+var eventTypes = map[string]uint32{
+	"workspace": 1<<31 | 0,
+	"output":    1<<31 | 1,
+	"mode":      1<<31 | 2,
+	"window":    1<<31 | 3,
+}
+
+// Server is a fake sway instance. Its methods may be called concurrently.
+type Server struct {
+	// Path is the path of the socket, as set in $SWAYSOCK.
+	Path string
+
+	t        testing.TB
+	listener net.Listener
+
+	mu          sync.Mutex
+	replies     map[uint32][]byte
+	commands    []string
+	conns       []net.Conn
+	subscribers map[net.Conn][]string // subscribed events of each connection
+	changed     chan struct{}         // closed and replaced when subscribers change
+}
+
+// NewServer starts a fake instance and sets $SWAYSOCK for the rest of the
+// test, clearing $I3SOCK. It is stopped when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	// Socket paths are limited to about a hundred bytes, which the
+	// directories of t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "i3test")
+	if err != nil {
+		t.Fatalf("i3test: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	s := &Server{
+		Path:        filepath.Join(dir, "ipc.sock"),
+		t:           t,
+		replies:     make(map[uint32][]byte),
+		subscribers: make(map[net.Conn][]string),
+		changed:     make(chan struct{}),
+	}
+
+	s.listener, err = net.Listen("unix", s.Path)
+	if err != nil {
+		t.Fatalf("i3test: %v", err)
+	}
+
+	t.Setenv("SWAYSOCK", s.Path)
+	t.Setenv("I3SOCK", "")
+
+	go s.serve()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetJSON sets the reply to messages of type typ, such as GetWorkspaces or
+// GetTree, to v encoded as JSON. Queries without a reply get an empty list,
+// and commands get a single success.
+func (s *Server) SetJSON(typ uint32, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatalf("i3test: unable to encode reply to message %d: %v", typ, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replies[typ] = data
+}
+
+// Commands returns the payloads of the RUN_COMMAND messages received so
+// far, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// Emit sends an event, e.g. "workspace" or "window", with v encoded as JSON
+// as its payload to every connection subscribed to it.
+func (s *Server) Emit(event string, v any) {
+	typ, ok := eventTypes[event]
+	if !ok {
+		s.t.Fatalf("i3test: unknown event %q", event)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatalf("i3test: unable to encode %s event: %v", event, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, events := range s.subscribers {
+		if !slices.Contains(events, event) {
+			continue
+		}
+
+		if err := write(conn, typ, data); err != nil {
+			s.t.Logf("i3test: unable to emit event: %v", err)
+		}
+	}
+}
+
+// Subscribers returns the number of connections subscribed to events.
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.subscribers)
+}
+
+// WaitSubscribers waits until at least n connections are subscribed to
+// events, and fails the test if that takes more than five seconds.
+func (s *Server) WaitSubscribers(n int) {
+	s.t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		count, changed := len(s.subscribers), s.changed
+		s.mu.Unlock()
+
+		if count >= n {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			s.t.Fatalf("i3test: %d connections subscribed, want %d", count, n)
+		}
+	}
+}
+
+// DropConnections closes every open connection, as happens when the
+// compositor exits. Clients can connect again afterwards.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	clear(s.subscribers)
+	s.notifyLocked()
+}
+
+// Close stops the instance. It is called automatically when the test ends.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.DropConnections()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers the messages of a connection until it is closed.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.forget(conn)
+
+	for {
+		typ, payload, err := read(conn)
+		if err != nil {
+			return
+		}
+
+		reply := s.reply(conn, typ, payload)
+
+		// Events are written under the lock too, so they never interleave
+		// with replies
+		s.mu.Lock()
+		err = write(conn, typ, reply)
+		s.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// reply records a message and returns its reply.
+func (s *Server) reply(conn net.Conn, typ uint32, payload []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch typ {
+	case RunCommand:
+		s.commands = append(s.commands, string(payload))
+	case Subscribe:
+		var events []string
+		if err := json.Unmarshal(payload, &events); err != nil {
+			return []byte(`{"success":false}`)
+		}
+		s.subscribers[conn] = append(s.subscribers[conn], events...)
+		s.notifyLocked()
+		return []byte(`{"success":true}`)
+	}
+
+	if reply, ok := s.replies[typ]; ok {
+		return reply
+	}
+
+	if typ == RunCommand {
+		return []byte(`[{"success":true}]`)
+	}
+	return []byte(`[]`)
+}
+
+// forget closes a connection and drops its subscription.
+func (s *Server) forget(conn net.Conn) {
+	conn.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conns = slices.DeleteFunc(s.conns, func(other net.Conn) bool {
+		return other == conn
+	})
+
+	if _, ok := s.subscribers[conn]; ok {
+		delete(s.subscribers, conn)
+		s.notifyLocked()
+	}
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func read(conn net.Conn) (uint32, []byte, error) {
+	header := make([]byte, len(magic)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if !bytes.Equal(header[:len(magic)], []byte(magic)) {
+		return 0, nil, fmt.Errorf("invalid header %q", header)
+	}
+
+	payload := make([]byte, binary.NativeEndian.Uint32(header[len(magic):]))
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return binary.NativeEndian.Uint32(header[len(magic)+4:]), payload, nil
+}
+
+func write(conn net.Conn, typ uint32, payload []byte) error {
+	header := make([]byte, len(magic)+8)
+	copy(header, magic)
+	binary.NativeEndian.PutUint32(header[len(magic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(header[len(magic)+4:], typ)
+
+	_, err := conn.Write(append(header, payload...))
+	return err
+}
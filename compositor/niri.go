@@ -0,0 +1,578 @@
+package compositor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Niri follows niri through its JSON IPC. Requests and replies are single
+// lines of JSON; the event stream starts with the full list of workspaces
+// and windows and then reports every change, so the state is kept up to
+// date from events alone.
+type Niri struct {
+	ctx      context.Context
+	socket   string
+	dispatch func(func())
+
+	// Accessed on the main thread only
+	state      State
+	workspaces []niriWorkspace // used to resolve workspaces to switch to
+	loaded     bool
+	running    bool
+	failure    error
+	watchers   map[*Watcher]bool
+}
+
+// NiriSocket returns the IPC socket of the running niri instance, or "" if
+// there is none.
+func NiriSocket() string {
+	return os.Getenv("NIRI_SOCKET")
+}
+
+// NewNiri returns a backend for the IPC socket at socket. Callbacks are
+// delivered through dispatch, which must call its argument on the GTK main
+// thread.
+func NewNiri(ctx context.Context, socket string, dispatch func(func())) *Niri {
+	return &Niri{
+		ctx:      ctx,
+		socket:   socket,
+		dispatch: dispatch,
+		watchers: make(map[*Watcher]bool),
+	}
+}
+
+func (n *Niri) Name() string {
+	return KindNiri
+}
+
+func (n *Niri) Watch(w Watcher) func() {
+	watcher := &w
+	n.watchers[watcher] = true
+
+	if !n.running {
+		n.running = true
+		go n.run()
+	}
+
+	n.dispatch(func() {
+		if !n.watchers[watcher] {
+			return
+		}
+		if n.failure != nil && w.Report != nil {
+			w.Report(n.failure)
+		}
+		if n.loaded {
+			w.Changed()
+		}
+	})
+
+	return func() {
+		delete(n.watchers, watcher)
+	}
+}
+
+func (n *Niri) State() State {
+	return n.state
+}
+
+// SwitchWorkspace focuses a workspace. It must be called on the main
+// thread, as the workspace is resolved against the current state.
+func (n *Niri) SwitchWorkspace(ctx context.Context, ws Workspace) error {
+	// Workspaces niri does not have are addressed by their index on the
+	// focused output, which focuses the empty workspace if there are fewer.
+	// Their output is focused first, so that they open there.
+	var reference map[string]any
+	for _, nws := range n.workspaces {
+		if nws.Output == ws.Output && nws.name() == ws.Name {
+			reference = map[string]any{"Id": nws.ID}
+			break
+		}
+	}
+	if reference == nil {
+		if ws.ID <= 0 {
+			return fmt.Errorf("unknown workspace %q", ws.Name)
+		}
+		reference = map[string]any{"Index": ws.ID}
+
+		if ws.Output != "" {
+			focus := map[string]any{
+				"Action": map[string]any{
+					"FocusMonitor": map[string]any{
+						"output": ws.Output,
+					},
+				},
+			}
+			if err := n.request(ctx, focus, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	action := map[string]any{
+		"Action": map[string]any{
+			"FocusWorkspace": map[string]any{
+				"reference": reference,
+			},
+		},
+	}
+
+	return n.request(ctx, action, nil)
+}
+
+// niriWorkspace is a workspace as niri describes it.
+type niriWorkspace struct {
+	ID             uint64  `json:"id"`
+	Idx            int     `json:"idx"`
+	Name           *string `json:"name"`
+	Output         string  `json:"output"`
+	IsUrgent       bool    `json:"is_urgent"`
+	IsActive       bool    `json:"is_active"`
+	IsFocused      bool    `json:"is_focused"`
+	ActiveWindowID *uint64 `json:"active_window_id"`
+}
+
+// name returns the name of the workspace, or its index on its output for
+// unnamed ones.
+func (ws niriWorkspace) name() string {
+	if ws.Name != nil {
+		return *ws.Name
+	}
+
+	return strconv.Itoa(ws.Idx)
+}
+
+// niriWindow is a window as niri describes it.
+type niriWindow struct {
+	ID          uint64  `json:"id"`
+	Title       *string `json:"title"`
+	AppID       *string `json:"app_id"`
+	WorkspaceID *uint64 `json:"workspace_id"`
+	IsFocused   bool    `json:"is_focused"`
+	IsUrgent    bool    `json:"is_urgent"`
+}
+
+// niriOutput is an output as niri describes it. Logical is nil for
+// disabled outputs.
+type niriOutput struct {
+	Name    string `json:"name"`
+	Logical *struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"logical"`
+}
+
+// niriEvent holds the events of the event stream the backend follows. Each
+// line sets exactly one field; other events leave them all nil.
+type niriEvent struct {
+	WorkspacesChanged *struct {
+		Workspaces []niriWorkspace `json:"workspaces"`
+	}
+	WorkspaceActivated *struct {
+		ID      uint64 `json:"id"`
+		Focused bool   `json:"focused"`
+	}
+	WorkspaceActiveWindowChanged *struct {
+		WorkspaceID    uint64  `json:"workspace_id"`
+		ActiveWindowID *uint64 `json:"active_window_id"`
+	}
+	WorkspaceUrgencyChanged *struct {
+		ID     uint64 `json:"id"`
+		Urgent bool   `json:"urgent"`
+	}
+	WindowsChanged *struct {
+		Windows []niriWindow `json:"windows"`
+	}
+	WindowOpenedOrChanged *struct {
+		Window niriWindow `json:"window"`
+	}
+	WindowClosed *struct {
+		ID uint64 `json:"id"`
+	}
+	WindowFocusChanged *struct {
+		ID *uint64 `json:"id"`
+	}
+	WindowUrgencyChanged *struct {
+		ID     uint64 `json:"id"`
+		Urgent bool   `json:"urgent"`
+	}
+}
+
+// niriModel is the state of niri as built from the event stream.
+type niriModel struct {
+	workspaces    []niriWorkspace
+	windows       []niriWindow
+	outputs       map[string]niriOutput
+	hasWorkspaces bool
+	hasWindows    bool
+}
+
+// apply updates the model with an event.
+func (m *niriModel) apply(ev niriEvent) {
+	switch {
+	case ev.WorkspacesChanged != nil:
+		m.workspaces = ev.WorkspacesChanged.Workspaces
+		m.hasWorkspaces = true
+
+	case ev.WorkspaceActivated != nil:
+		activated := m.workspace(ev.WorkspaceActivated.ID)
+		if activated == nil {
+			return
+		}
+
+		for i := range m.workspaces {
+			ws := &m.workspaces[i]
+			if ws.Output == activated.Output {
+				ws.IsActive = ws.ID == activated.ID
+			}
+			if ev.WorkspaceActivated.Focused {
+				ws.IsFocused = ws.ID == activated.ID
+			}
+		}
+
+	case ev.WorkspaceActiveWindowChanged != nil:
+		if ws := m.workspace(ev.WorkspaceActiveWindowChanged.WorkspaceID); ws != nil {
+			ws.ActiveWindowID = ev.WorkspaceActiveWindowChanged.ActiveWindowID
+		}
+
+	case ev.WorkspaceUrgencyChanged != nil:
+		if ws := m.workspace(ev.WorkspaceUrgencyChanged.ID); ws != nil {
+			ws.IsUrgent = ev.WorkspaceUrgencyChanged.Urgent
+		}
+
+	case ev.WindowsChanged != nil:
+		m.windows = ev.WindowsChanged.Windows
+		m.hasWindows = true
+
+	case ev.WindowOpenedOrChanged != nil:
+		window := ev.WindowOpenedOrChanged.Window
+		if window.IsFocused {
+			for i := range m.windows {
+				m.windows[i].IsFocused = false
+			}
+		}
+
+		if w := m.window(window.ID); w != nil {
+			*w = window
+		} else {
+			m.windows = append(m.windows, window)
+		}
+
+	case ev.WindowClosed != nil:
+		for i, w := range m.windows {
+			if w.ID == ev.WindowClosed.ID {
+				m.windows = append(m.windows[:i], m.windows[i+1:]...)
+				break
+			}
+		}
+
+	case ev.WindowFocusChanged != nil:
+		for i := range m.windows {
+			w := &m.windows[i]
+			w.IsFocused = ev.WindowFocusChanged.ID != nil && w.ID == *ev.WindowFocusChanged.ID
+		}
+
+	case ev.WindowUrgencyChanged != nil:
+		if w := m.window(ev.WindowUrgencyChanged.ID); w != nil {
+			w.IsUrgent = ev.WindowUrgencyChanged.Urgent
+		}
+	}
+}
+
+func (m *niriModel) workspace(id uint64) *niriWorkspace {
+	for i := range m.workspaces {
+		if m.workspaces[i].ID == id {
+			return &m.workspaces[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *niriModel) window(id uint64) *niriWindow {
+	for i := range m.windows {
+		if m.windows[i].ID == id {
+			return &m.windows[i]
+		}
+	}
+
+	return nil
+}
+
+// state converts the model. Outputs are derived from the workspaces, as
+// the event stream does not report them.
+func (m *niriModel) state() State {
+	var state State
+
+	workspaces := append([]niriWorkspace(nil), m.workspaces...)
+	sort.SliceStable(workspaces, func(i, j int) bool {
+		if workspaces[i].Output != workspaces[j].Output {
+			return workspaces[i].Output < workspaces[j].Output
+		}
+		return workspaces[i].Idx < workspaces[j].Idx
+	})
+
+	// niri does not number outputs, so they get their index in name order
+	outputNames := make([]string, 0, len(m.outputs))
+	for name := range m.outputs {
+		outputNames = append(outputNames, name)
+	}
+	sort.Strings(outputNames)
+
+	outputIDs := make(map[string]int)
+	for i, name := range outputNames {
+		outputIDs[name] = i
+	}
+
+	names := make(map[uint64]string)
+	outputs := make(map[uint64]string)
+	for _, ws := range workspaces {
+		names[ws.ID] = ws.name()
+		outputs[ws.ID] = ws.Output
+
+		state.Workspaces = append(state.Workspaces, Workspace{
+			ID:      ws.Idx,
+			Name:    ws.name(),
+			Output:  ws.Output,
+			Focused: ws.IsFocused,
+			Visible: ws.IsActive,
+			Urgent:  ws.IsUrgent,
+		})
+
+		if ws.IsActive {
+			output := Output{
+				ID:              outputIDs[ws.Output],
+				Name:            ws.Output,
+				Focused:         ws.IsFocused,
+				ActiveWorkspace: ws.name(),
+			}
+			if o, ok := m.outputs[ws.Output]; ok && o.Logical != nil {
+				output.X, output.Y = o.Logical.X, o.Logical.Y
+			}
+
+			state.Outputs = append(state.Outputs, output)
+		}
+	}
+
+	for _, w := range m.windows {
+		window := Window{
+			ID:      strconv.FormatUint(w.ID, 10),
+			Focused: w.IsFocused,
+			Urgent:  w.IsUrgent,
+		}
+		if w.AppID != nil {
+			window.AppID = *w.AppID
+		}
+		if w.Title != nil {
+			window.Title = *w.Title
+		}
+		if w.WorkspaceID != nil {
+			window.Workspace = names[*w.WorkspaceID]
+			window.Output = outputs[*w.WorkspaceID]
+		}
+
+		state.Windows = append(state.Windows, window)
+	}
+
+	return state
+}
+
+// run follows the compositor until the context is done, reconnecting with
+// backoff.
+func (n *Niri) run() {
+	backoff := libs.Backoff{Min: time.Second, Max: time.Minute}
+
+	for {
+		connected, err := n.listen()
+		if n.ctx.Err() != nil {
+			return
+		}
+
+		if connected {
+			backoff.Reset()
+		}
+
+		libs.Log.Println("unable to follow niri:", err)
+		n.dispatch(func() {
+			n.report(fmt.Errorf("unable to follow niri: %w (retrying)", err))
+		})
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
+
+// listen reads the event stream and applies every event. It blocks until
+// the context is done or the connection fails, and reports whether it got
+// connected.
+func (n *Niri) listen() (bool, error) {
+	conn, reader, err := n.send(n.ctx, "EventStream")
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(n.ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if err := readNiriReply(reader, nil); err != nil {
+		return false, err
+	}
+
+	var model niriModel
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("connection closed by niri")
+			}
+			return true, err
+		}
+
+		var ev niriEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			libs.Log.Println("unable to decode niri event:", err)
+			continue
+		}
+
+		model.apply(ev)
+
+		// Outputs are not part of the event stream, but adding or removing
+		// one moves workspaces around
+		if ev.WorkspacesChanged != nil {
+			var reply struct {
+				Outputs map[string]niriOutput `json:"Outputs"`
+			}
+			if err := n.request(n.ctx, "Outputs", &reply); err != nil {
+				return true, err
+			}
+			model.outputs = reply.Outputs
+		}
+
+		// Wait for the initial lists before showing anything
+		if !model.hasWorkspaces || !model.hasWindows {
+			continue
+		}
+
+		state := model.state()
+		workspaces := append([]niriWorkspace(nil), model.workspaces...)
+		n.dispatch(func() {
+			n.apply(state, workspaces)
+		})
+	}
+}
+
+// apply swaps in a new state on the main thread and notifies the watchers
+// if it changed.
+func (n *Niri) apply(state State, workspaces []niriWorkspace) {
+	changed := !n.loaded || !reflect.DeepEqual(state, n.state)
+	n.state = state
+	n.workspaces = workspaces
+	n.loaded = true
+	n.report(nil)
+
+	if changed {
+		for w := range n.watchers {
+			w.Changed()
+		}
+	}
+}
+
+// report hands failures to the watchers, on the main thread.
+func (n *Niri) report(err error) {
+	if err == nil && n.failure == nil {
+		return
+	}
+	n.failure = err
+
+	for w := range n.watchers {
+		if w.Report != nil {
+			w.Report(err)
+		}
+	}
+}
+
+// request sends a request on a fresh connection and decodes the reply into
+// v, if not nil.
+func (n *Niri) request(ctx context.Context, request any, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	conn, reader, err := n.send(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	return readNiriReply(reader, v)
+}
+
+// send connects to niri and writes a request.
+func (n *Niri) send(ctx context.Context, request any) (net.Conn, *bufio.Reader, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to encode niri request: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", n.socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to niri socket: %w", err)
+	}
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("unable to send niri request: %w", err)
+	}
+
+	return conn, bufio.NewReader(conn), nil
+}
+
+// readNiriReply reads a reply, which is either {"Ok": ...} or
+// {"Err": "..."}, and decodes the Ok value into v, if not nil.
+func readNiriReply(reader *bufio.Reader, v any) error {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("unable to read niri reply: %w", err)
+	}
+
+	var reply struct {
+		Ok  json.RawMessage `json:"Ok"`
+		Err *string         `json:"Err"`
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return fmt.Errorf("unable to parse niri reply: %w", err)
+	}
+
+	if reply.Err != nil {
+		return fmt.Errorf("niri refused the request: %s", *reply.Err)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(reply.Ok, v); err != nil {
+			return fmt.Errorf("unable to parse niri reply: %w", err)
+		}
+	}
+
+	return nil
+}
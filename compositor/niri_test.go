@@ -0,0 +1,130 @@
+package compositor
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// serveNiriRequests accepts requests on a niri socket, answering each with
+// "Handled", and returns the socket path and a function listing the
+// requests received so far.
+func serveNiriRequests(t *testing.T) (string, func() []string) {
+	t.Helper()
+
+	// Socket paths are limited to about a hundred bytes, which the
+	// directories of t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "niri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "niri.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	var mu sync.Mutex
+	var requests []string
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil {
+				mu.Lock()
+				requests = append(requests, line[:len(line)-1])
+				mu.Unlock()
+
+				conn.Write([]byte(`{"Ok":"Handled"}` + "\n"))
+			}
+			conn.Close()
+		}
+	}()
+
+	return path, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return slices.Clone(requests)
+	}
+}
+
+func TestNiriSwitchWorkspace(t *testing.T) {
+	name := "web"
+	workspaces := []niriWorkspace{
+		{ID: 7, Idx: 1, Output: "DP-1"},
+		{ID: 8, Idx: 2, Name: &name, Output: "DP-1"},
+	}
+
+	tests := []struct {
+		name string
+		ws   Workspace
+		want []string
+	}{
+		{
+			name: "existing workspace",
+			ws:   Workspace{ID: 2, Name: "web", Output: "DP-1"},
+			want: []string{`{"Action":{"FocusWorkspace":{"reference":{"Id":8}}}}`},
+		},
+		{
+			name: "new workspace on an output",
+			ws:   Workspace{ID: 3, Name: "3", Output: "HDMI-A-1"},
+			want: []string{
+				`{"Action":{"FocusMonitor":{"output":"HDMI-A-1"}}}`,
+				`{"Action":{"FocusWorkspace":{"reference":{"Index":3}}}}`,
+			},
+		},
+		{
+			name: "new workspace of no output",
+			ws:   Workspace{ID: 3, Name: "3"},
+			want: []string{`{"Action":{"FocusWorkspace":{"reference":{"Index":3}}}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socket, requests := serveNiriRequests(t)
+
+			n := NewNiri(context.Background(), socket, func(f func()) { f() })
+			n.workspaces = workspaces
+
+			if err := n.SwitchWorkspace(context.Background(), tt.ws); err != nil {
+				t.Fatalf("SwitchWorkspace() error = %v", err)
+			}
+
+			if got := requests(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SwitchWorkspace() requests =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown named workspace", func(t *testing.T) {
+		socket, requests := serveNiriRequests(t)
+
+		n := NewNiri(context.Background(), socket, func(f func()) { f() })
+		if err := n.SwitchWorkspace(context.Background(), Workspace{ID: -1, Name: "mail"}); err == nil {
+			t.Error("SwitchWorkspace() succeeded, want an error")
+		}
+		if got := requests(); len(got) != 0 {
+			t.Errorf("SwitchWorkspace() sent %q, want nothing", got)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package compositor
+
+import (
+	"context"
+	"errors"
+)
+
+// None stands in when the bar runs under no supported compositor. Its
+// state is always empty, so compositor widgets show nothing.
+type None struct {
+	dispatch func(func())
+}
+
+// NewNone returns the fallback backend. Callbacks are delivered through
+// dispatch, which must call its argument on the GTK main thread.
+func NewNone(dispatch func(func())) *None {
+	return &None{dispatch: dispatch}
+}
+
+func (n *None) Name() string {
+	return KindNone
+}
+
+func (n *None) Watch(w Watcher) func() {
+	stopped := false
+
+	// Render the empty state once, replacing whatever the widget shows
+	// after Create
+	n.dispatch(func() {
+		if !stopped {
+			w.Changed()
+		}
+	})
+
+	return func() {
+		stopped = true
+	}
+}
+
+func (n *None) State() State {
+	return State{}
+}
+
+func (n *None) SwitchWorkspace(ctx context.Context, ws Workspace) error {
+	return errors.New("no supported compositor")
+}
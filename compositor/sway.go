@@ -0,0 +1,394 @@
+package compositor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Sway follows sway, or i3, through the i3 IPC protocol. One connection
+// subscribes to workspace, window and output events; after each burst of
+// events the state is queried again on a second connection.
+type Sway struct {
+	ctx      context.Context
+	socket   string
+	dispatch func(func())
+
+	// Accessed on the main thread only
+	state    State
+	loaded   bool
+	running  bool
+	failure  error
+	watchers map[*Watcher]bool
+}
+
+// SwaySocket returns the IPC socket of the running sway or i3 instance, or
+// "" if there is none.
+func SwaySocket() string {
+	if socket := os.Getenv("SWAYSOCK"); socket != "" {
+		return socket
+	}
+
+	return os.Getenv("I3SOCK")
+}
+
+// NewSway returns a backend for the IPC socket at socket. Callbacks are
+// delivered through dispatch, which must call its argument on the GTK main
+// thread.
+func NewSway(ctx context.Context, socket string, dispatch func(func())) *Sway {
+	return &Sway{
+		ctx:      ctx,
+		socket:   socket,
+		dispatch: dispatch,
+		watchers: make(map[*Watcher]bool),
+	}
+}
+
+func (s *Sway) Name() string {
+	return KindSway
+}
+
+func (s *Sway) Watch(w Watcher) func() {
+	watcher := &w
+	s.watchers[watcher] = true
+
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+
+	s.dispatch(func() {
+		if !s.watchers[watcher] {
+			return
+		}
+		if s.failure != nil && w.Report != nil {
+			w.Report(s.failure)
+		}
+		if s.loaded {
+			w.Changed()
+		}
+	})
+
+	return func() {
+		delete(s.watchers, watcher)
+	}
+}
+
+func (s *Sway) State() State {
+	return s.state
+}
+
+// SwitchWorkspace focuses a workspace. It must be called on the main
+// thread, as the workspace is looked up in the current state.
+func (s *Sway) SwitchWorkspace(ctx context.Context, ws Workspace) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	conn, err := dialI3ipc(ctx, s.socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.conn.SetDeadline(deadline)
+	}
+
+	command := "workspace " + strconv.Quote(ws.Name)
+	if ws.Output != "" && !s.state.Exists(ws) {
+		// New workspaces open on the focused output
+		command = "focus output " + strconv.Quote(ws.Output) + "; " + command
+	}
+
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := conn.request(i3ipcRunCommand, []byte(command), &results); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if !result.Success {
+			return fmt.Errorf("%s: %s", command, result.Error)
+		}
+	}
+
+	return nil
+}
+
+// run follows the compositor until the context is done, reconnecting with
+// backoff.
+func (s *Sway) run() {
+	backoff := libs.Backoff{Min: time.Second, Max: time.Minute}
+
+	for {
+		connected, err := s.listen()
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		if connected {
+			backoff.Reset()
+		}
+
+		libs.Log.Println("unable to follow sway:", err)
+		s.dispatch(func() {
+			s.report(fmt.Errorf("unable to follow sway: %w (retrying)", err))
+		})
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
+
+// listen subscribes to events and refreshes the state after each of them.
+// It blocks until the context is done or the connection fails, and reports
+// whether it got connected.
+func (s *Sway) listen() (bool, error) {
+	conn, err := dialI3ipc(s.ctx, s.socket)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(s.ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	var subscribed struct {
+		Success bool `json:"success"`
+	}
+	if err := conn.request(i3ipcSubscribe, []byte(`["workspace","window","output"]`), &subscribed); err != nil {
+		return false, err
+	}
+	if !subscribed.Success {
+		return false, errors.New("subscription refused")
+	}
+
+	// Refresh in the background so that reading events never waits for
+	// queries; events arriving during a refresh cause one more
+	wake := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-wake:
+			}
+
+			if err := s.refresh(); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	wake <- struct{}{}
+
+	for {
+		if _, _, err := conn.receive(); err != nil {
+			return true, err
+		}
+
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// refresh queries the state and applies it on the main thread.
+func (s *Sway) refresh() error {
+	state, err := s.query()
+	if err != nil {
+		if s.ctx.Err() == nil {
+			libs.Log.Println("unable to query sway:", err)
+		}
+		return err
+	}
+
+	s.dispatch(func() {
+		changed := !s.loaded || !reflect.DeepEqual(state, s.state)
+		s.state = state
+		s.loaded = true
+		s.report(nil)
+
+		if changed {
+			for w := range s.watchers {
+				w.Changed()
+			}
+		}
+	})
+
+	return nil
+}
+
+// report hands failures to the watchers, on the main thread.
+func (s *Sway) report(err error) {
+	if err == nil && s.failure == nil {
+		return
+	}
+	s.failure = err
+
+	for w := range s.watchers {
+		if w.Report != nil {
+			w.Report(err)
+		}
+	}
+}
+
+// swayNode is a node of the layout tree.
+type swayNode struct {
+	ID               int64   `json:"id"`
+	Type             string  `json:"type"`
+	Name             string  `json:"name"`
+	Focused          bool    `json:"focused"`
+	Urgent           bool    `json:"urgent"`
+	AppID            *string `json:"app_id"` // sway only
+	Window           *int64  `json:"window"` // X11 windows
+	WindowProperties *struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+// query fetches outputs, workspaces and windows.
+func (s *Sway) query() (State, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 2*time.Second)
+	defer cancel()
+
+	conn, err := dialI3ipc(ctx, s.socket)
+	if err != nil {
+		return State{}, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.conn.SetDeadline(deadline)
+	}
+
+	var workspaces []struct {
+		Num     int    `json:"num"`
+		Name    string `json:"name"`
+		Visible bool   `json:"visible"`
+		Focused bool   `json:"focused"`
+		Urgent  bool   `json:"urgent"`
+		Output  string `json:"output"`
+	}
+	if err := conn.request(i3ipcGetWorkspaces, nil, &workspaces); err != nil {
+		return State{}, err
+	}
+
+	var outputs []struct {
+		Name             string `json:"name"`
+		Active           bool   `json:"active"`
+		Focused          bool   `json:"focused"` // sway only
+		CurrentWorkspace string `json:"current_workspace"`
+		Rect             struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"rect"`
+	}
+	if err := conn.request(i3ipcGetOutputs, nil, &outputs); err != nil {
+		return State{}, err
+	}
+
+	var tree swayNode
+	if err := conn.request(i3ipcGetTree, nil, &tree); err != nil {
+		return State{}, err
+	}
+
+	var state State
+
+	focusedOutput := ""
+	for _, ws := range workspaces {
+		state.Workspaces = append(state.Workspaces, Workspace{
+			ID:      ws.Num,
+			Name:    ws.Name,
+			Output:  ws.Output,
+			Focused: ws.Focused,
+			Visible: ws.Visible,
+			Urgent:  ws.Urgent,
+		})
+		if ws.Focused {
+			focusedOutput = ws.Output
+		}
+	}
+
+	for _, o := range outputs {
+		// i3 lists disabled outputs and a fake "xroot-0" one
+		if !o.Active {
+			continue
+		}
+
+		// IPC does not number outputs, so they get their index
+		state.Outputs = append(state.Outputs, Output{
+			ID:              len(state.Outputs),
+			Name:            o.Name,
+			Focused:         o.Focused || o.Name == focusedOutput,
+			X:               o.Rect.X,
+			Y:               o.Rect.Y,
+			ActiveWorkspace: o.CurrentWorkspace,
+		})
+	}
+
+	collectWindows(&tree, "", "", &state.Windows)
+
+	return state, nil
+}
+
+// collectWindows appends the windows below node, which is part of the
+// named output and workspace.
+func collectWindows(node *swayNode, output, workspace string, windows *[]Window) {
+	switch node.Type {
+	case "output":
+		output = node.Name
+	case "workspace":
+		workspace = node.Name
+	}
+
+	children := len(node.Nodes) + len(node.FloatingNodes)
+	isWindow := children == 0 && (node.Type == "con" || node.Type == "floating_con") &&
+		(node.AppID != nil || node.Window != nil)
+
+	if isWindow && workspace != "" {
+		appID := ""
+		if node.AppID != nil {
+			appID = *node.AppID
+		} else if node.WindowProperties != nil {
+			appID = node.WindowProperties.Class
+		}
+
+		*windows = append(*windows, Window{
+			ID:        strconv.FormatInt(node.ID, 10),
+			AppID:     appID,
+			Title:     node.Name,
+			Workspace: workspace,
+			Output:    output,
+			Focused:   node.Focused,
+			Urgent:    node.Urgent,
+		})
+	}
+
+	for i := range node.Nodes {
+		collectWindows(&node.Nodes[i], output, workspace, windows)
+	}
+	for i := range node.FloatingNodes {
+		collectWindows(&node.FloatingNodes[i], output, workspace, windows)
+	}
+}
@@ -0,0 +1,280 @@
+package compositor
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grentenrg/go-bar/compositor/i3test"
+)
+
+// serveSway sets up srv with workspaces 1 and "2:web" on DP-1, where 1 has
+// focus, and workspace 3 on HDMI-A-1.
+func serveSway(srv *i3test.Server) {
+	srv.SetJSON(i3test.GetWorkspaces, []map[string]any{
+		{"num": 1, "name": "1", "output": "DP-1", "focused": true, "visible": true},
+		{"num": 2, "name": "2:web", "output": "DP-1"},
+		{"num": 3, "name": "3", "output": "HDMI-A-1", "visible": true, "urgent": true},
+	})
+	srv.SetJSON(i3test.GetOutputs, []map[string]any{
+		{"name": "DP-1", "active": true, "focused": true, "current_workspace": "1", "rect": map[string]int{"x": 0, "y": 0}},
+		{"name": "HDMI-A-1", "active": true, "current_workspace": "3", "rect": map[string]int{"x": 1920, "y": 0}},
+		{"name": "xroot-0", "active": false},
+	})
+	srv.SetJSON(i3test.GetTree, swayTree("shell"))
+}
+
+// swayTree returns a layout tree with a terminal titled title on workspace
+// 1 and an X11 browser floating on workspace 3.
+func swayTree(title string) map[string]any {
+	return map[string]any{
+		"id": 1, "type": "root", "name": "root",
+		"nodes": []map[string]any{
+			{
+				"id": 2, "type": "output", "name": "DP-1",
+				"nodes": []map[string]any{
+					{
+						"id": 3, "type": "workspace", "name": "1",
+						"nodes": []map[string]any{
+							{"id": 10, "type": "con", "name": title, "app_id": "kitty", "focused": true},
+						},
+					},
+					{"id": 4, "type": "workspace", "name": "2:web"},
+				},
+			},
+			{
+				"id": 5, "type": "output", "name": "HDMI-A-1",
+				"nodes": []map[string]any{
+					{
+						"id": 6, "type": "workspace", "name": "3",
+						"floating_nodes": []map[string]any{
+							{"id": 11, "type": "floating_con", "name": "news", "window": 4242,
+								"window_properties": map[string]any{"class": "Firefox"}, "urgent": true},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newTestSway starts a backend following srv and waits for its first
+// state. It returns the errors reported to its watcher.
+func newTestSway(t *testing.T, srv *i3test.Server) (*Sway, *mainThread, chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	m := newMainThread(t)
+	s := NewSway(ctx, srv.Path, m.dispatch)
+
+	reports, stop := watch(m, s)
+	t.Cleanup(stop)
+
+	srv.WaitSubscribers(1)
+	waitState(t, m, s, "the first state", func(s State) bool {
+		return len(s.Outputs) > 0
+	})
+
+	return s, m, reports
+}
+
+func TestSwayState(t *testing.T) {
+	srv := i3test.NewServer(t)
+	serveSway(srv)
+
+	s, m, _ := newTestSway(t, srv)
+
+	var got State
+	m.run(func() {
+		got = s.State()
+	})
+
+	want := State{
+		Outputs: []Output{
+			{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: "1"},
+			{ID: 1, Name: "HDMI-A-1", X: 1920, ActiveWorkspace: "3"},
+		},
+		Workspaces: []Workspace{
+			{ID: 1, Name: "1", Output: "DP-1", Focused: true, Visible: true},
+			{ID: 2, Name: "2:web", Output: "DP-1"},
+			{ID: 3, Name: "3", Output: "HDMI-A-1", Visible: true, Urgent: true},
+		},
+		Windows: []Window{
+			{ID: "10", AppID: "kitty", Title: "shell", Workspace: "1", Output: "DP-1", Focused: true},
+			{ID: "11", AppID: "Firefox", Title: "news", Workspace: "3", Output: "HDMI-A-1", Urgent: true},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("State() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSwayRefreshOnEvents(t *testing.T) {
+	srv := i3test.NewServer(t)
+	serveSway(srv)
+
+	s, m, _ := newTestSway(t, srv)
+
+	srv.SetJSON(i3test.GetTree, swayTree("vim"))
+	srv.Emit("window", map[string]any{"change": "title"})
+
+	waitState(t, m, s, "the new title", func(s State) bool {
+		w := s.FocusedWindow()
+		return w != nil && w.Title == "vim"
+	})
+}
+
+func TestSwayReconnect(t *testing.T) {
+	srv := i3test.NewServer(t)
+	serveSway(srv)
+
+	s, m, reports := newTestSway(t, srv)
+
+	// The compositor goes away, and comes back with another workspace
+	srv.SetJSON(i3test.GetWorkspaces, []map[string]any{
+		{"num": 4, "name": "4", "output": "DP-1", "focused": true, "visible": true},
+	})
+	srv.DropConnections()
+
+	select {
+	case err := <-reports:
+		if err == nil {
+			t.Fatal("reported recovery before the failure")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection loss not reported")
+	}
+
+	srv.WaitSubscribers(1)
+	waitState(t, m, s, "the state after reconnecting", func(s State) bool {
+		ws := s.FocusedWorkspace()
+		return ws != nil && ws.Name == "4"
+	})
+
+	select {
+	case err := <-reports:
+		if err != nil {
+			t.Errorf("reported %v after reconnecting, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("recovery not reported")
+	}
+}
+
+func TestSwaySwitchWorkspace(t *testing.T) {
+	tests := []struct {
+		name string
+		ws   Workspace
+		want []string
+	}{
+		{
+			name: "existing",
+			ws:   Workspace{ID: 2, Name: "2:web", Output: "DP-1"},
+			want: []string{`workspace "2:web"`},
+		},
+		{
+			name: "quoted",
+			ws:   Workspace{Name: `say "hi"`},
+			want: []string{`workspace "say \"hi\""`},
+		},
+		{
+			name: "new on an output",
+			ws:   Workspace{ID: 5, Name: "5", Output: "HDMI-A-1"},
+			want: []string{`focus output "HDMI-A-1"; workspace "5"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := i3test.NewServer(t)
+			serveSway(srv)
+
+			s, m, _ := newTestSway(t, srv)
+
+			var err error
+			m.run(func() {
+				err = s.SwitchWorkspace(context.Background(), tt.ws)
+			})
+			if err != nil {
+				t.Fatalf("SwitchWorkspace() error = %v", err)
+			}
+
+			if got := srv.Commands(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwaySwitchWorkspaceFailure(t *testing.T) {
+	srv := i3test.NewServer(t)
+	serveSway(srv)
+
+	s, m, _ := newTestSway(t, srv)
+
+	srv.SetJSON(i3test.RunCommand, []map[string]any{{"success": false, "error": "Invalid output"}})
+
+	var err error
+	m.run(func() {
+		err = s.SwitchWorkspace(context.Background(), Workspace{ID: 5, Name: "5", Output: "DP-9"})
+	})
+	if err == nil {
+		t.Fatal("SwitchWorkspace() succeeded, want the command error")
+	}
+}
+
+func TestSwaySwitchWorkspaceTimeout(t *testing.T) {
+	// A wedged sway accepts connections but never replies
+	dir, err := os.MkdirTemp("", "sway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "ipc.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	})
+
+	s := NewSway(context.Background(), listener.Addr().String(), func(f func()) { f() })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.SwitchWorkspace(context.Background(), Workspace{ID: 1, Name: "1"})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("SwitchWorkspace() succeeded, want a timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SwitchWorkspace() still waiting for sway")
+	}
+}
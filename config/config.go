@@ -0,0 +1,285 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the configuration file inside the go-bar
+// configuration directory.
+const FileName = "config.json"
+
+// Config describes the layout of the bar: which widgets are shown in each
+// of the three sections and in which order.
+type Config struct {
+	Left   []WidgetConfig
+	Center []WidgetConfig
+	Right  []WidgetConfig
+}
+
+// WidgetConfig is a single widget entry of a section.
+type WidgetConfig struct {
+	// Type selects the widget implementation, e.g. "clock" or "disk".
+	Type string
+	// ID optionally names the entry so that it can be addressed from
+	// outside the bar, e.g. by "go-bar msg refresh <id>".
+	ID string
+	// Options holds every other key of the entry.
+	Options *Options
+}
+
+// SectionNames lists the sections of the bar from left to right.
+var SectionNames = []string{"left", "center", "right"}
+
+// Sections returns the sections of the bar keyed by their name.
+func (c *Config) Sections() map[string][]WidgetConfig {
+	return map[string][]WidgetConfig{
+		"left":   c.Left,
+		"center": c.Center,
+		"right":  c.Right,
+	}
+}
+
+// Dir returns the go-bar configuration directory, honouring
+// $XDG_CONFIG_HOME.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "go-bar"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "go-bar"), nil
+}
+
+// Dirs returns the directories searched for go-bar resources, most
+// important first: the user directory from Dir, then the go-bar directory
+// of every entry of $XDG_CONFIG_DIRS.
+func Dirs() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{dir}
+
+	systemDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if systemDirs == "" {
+		systemDirs = "/etc/xdg"
+	}
+
+	for _, systemDir := range filepath.SplitList(systemDirs) {
+		// Relative paths are invalid per the XDG specification
+		if filepath.IsAbs(systemDir) {
+			dirs = append(dirs, filepath.Join(systemDir, "go-bar"))
+		}
+	}
+
+	return dirs, nil
+}
+
+// Find returns the path of the first file called name in the directories
+// returned by Dirs. It returns an error wrapping fs.ErrNotExist if there is
+// none.
+func Find(name string) (string, error) {
+	dirs, err := Dirs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s not found in %s: %w", name, strings.Join(dirs, ", "), fs.ErrNotExist)
+}
+
+// Path returns the location of the configuration file in the user
+// configuration directory, whether it exists or not.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the first configuration file found by Find. A missing file is
+// not an error: the built-in default layout is returned instead.
+func Load() (*Config, error) {
+	path, err := Find(FileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return LoadFile(path)
+}
+
+// LoadFile reads and parses the configuration file at path.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse decodes a configuration document.
+func Parse(data []byte) (*Config, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, syntaxError(data, err)
+	}
+
+	cfg := &Config{}
+	sections := map[string]*[]WidgetConfig{
+		"left":   &cfg.Left,
+		"center": &cfg.Center,
+		"right":  &cfg.Right,
+	}
+
+	// Unknown sections are reported first, then the sections are checked
+	// from left to right, so that the same mistakes are always reported the
+	// same way
+	var unknown []string
+	for key := range raw {
+		if _, ok := sections[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &Error{Key: unknown[0], Err: errors.New("unknown section, expected left, center or right")}
+	}
+
+	for _, name := range SectionNames {
+		data, ok := raw[name]
+		if !ok {
+			continue
+		}
+
+		widgets, err := parseSection(name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		*sections[name] = widgets
+	}
+
+	return cfg, nil
+}
+
+func parseSection(name string, data json.RawMessage) ([]WidgetConfig, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, &Error{Key: name, Err: errors.New("expected a list of widgets")}
+	}
+
+	widgets := make([]WidgetConfig, 0, len(entries))
+	for i, entry := range entries {
+		path := fmt.Sprintf("%s[%d]", name, i)
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &fields); err != nil {
+			return nil, &Error{Key: path, Err: errors.New("expected a widget object")}
+		}
+
+		rawType, ok := fields["type"]
+		if !ok {
+			return nil, &Error{Key: path + ".type", Err: errors.New("missing widget type")}
+		}
+
+		var typ string
+		if err := json.Unmarshal(rawType, &typ); err != nil || typ == "" {
+			return nil, &Error{Key: path + ".type", Err: errors.New("expected a non-empty string")}
+		}
+		delete(fields, "type")
+
+		var id string
+		if rawID, ok := fields["id"]; ok {
+			if err := json.Unmarshal(rawID, &id); err != nil || id == "" {
+				return nil, &Error{Key: path + ".id", Err: errors.New("expected a non-empty string")}
+			}
+			delete(fields, "id")
+		}
+
+		widgets = append(widgets, WidgetConfig{
+			Type:    typ,
+			ID:      id,
+			Options: newOptions(path, fields),
+		})
+	}
+
+	return widgets, nil
+}
+
+// syntaxError converts JSON syntax errors into errors carrying the line and
+// column of the offending byte.
+func syntaxError(data []byte, err error) error {
+	var synErr *json.SyntaxError
+	if !errors.As(err, &synErr) {
+		return &Error{Err: errors.New("expected a JSON object with left, center and right sections")}
+	}
+
+	offset := synErr.Offset
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n') - 1
+
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// Error reports a problem with a specific key of the configuration.
+type Error struct {
+	Key string
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Equal reports whether two widget entries have the same type, ID and
+// options, in which case a running widget built from one can be kept for the
+// other.
+func (w WidgetConfig) Equal(other WidgetConfig) bool {
+	if w.Type != other.Type || w.ID != other.ID {
+		return false
+	}
+
+	return w.Options.equal(other.Options)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"left": [{"type": "workspace", "output": "bar"}],
+		"right": [{"type": "clock", "id": "time"}, {"type": "cpu"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(cfg.Left) != 1 || cfg.Left[0].Type != "workspace" || cfg.Left[0].Options.Path() != "left[0]" {
+		t.Errorf("Parse() left = %+v, want one workspace at left[0]", cfg.Left)
+	}
+	if len(cfg.Center) != 0 {
+		t.Errorf("Parse() center = %+v, want none", cfg.Center)
+	}
+	if len(cfg.Right) != 2 || cfg.Right[0].ID != "time" || cfg.Right[1].Options.Path() != "right[1]" {
+		t.Errorf("Parse() right = %+v, want the clock time and cpu", cfg.Right)
+	}
+
+	// Type and ID are not options
+	if err := cfg.Right[0].Options.CheckUnused(); err != nil {
+		t.Errorf("CheckUnused() error = %v, want nil", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		key  string
+		want string
+	}{
+		{
+			name: "syntax",
+			data: "{\n  \"left\": [,]\n}",
+			want: "line 2, column 12: invalid character ',' looking for beginning of value",
+		},
+		{
+			name: "not an object",
+			data: `[]`,
+			want: "expected a JSON object with left, center and right sections",
+		},
+		{
+			name: "unknown section",
+			data: `{"middle": [], "left": [{}]}`,
+			key:  "middle",
+			want: "middle: unknown section, expected left, center or right",
+		},
+		{
+			name: "first unknown section",
+			data: `{"top": [], "bottom": []}`,
+			key:  "bottom",
+			want: "bottom: unknown section, expected left, center or right",
+		},
+		{
+			name: "section not a list",
+			data: `{"center": {}}`,
+			key:  "center",
+			want: "center: expected a list of widgets",
+		},
+		{
+			name: "widget not an object",
+			data: `{"left": [{"type": "clock"}, "cpu"]}`,
+			key:  "left[1]",
+			want: "left[1]: expected a widget object",
+		},
+		{
+			name: "missing type",
+			data: `{"right": [{"id": "time"}]}`,
+			key:  "right[0].type",
+			want: "right[0].type: missing widget type",
+		},
+		{
+			name: "empty type",
+			data: `{"right": [{"type": ""}]}`,
+			key:  "right[0].type",
+			want: "right[0].type: expected a non-empty string",
+		},
+		{
+			name: "invalid ID",
+			data: `{"right": [{"type": "clock", "id": 3}]}`,
+			key:  "right[0].id",
+			want: "right[0].id: expected a non-empty string",
+		},
+		{
+			name: "sections from left to right",
+			data: `{"right": [{}], "center": [{}], "left": [{}]}`,
+			key:  "left[0].type",
+			want: "left[0].type: missing widget type",
+		},
+		{
+			name: "center before right",
+			data: `{"right": [{}], "center": [{}]}`,
+			key:  "center[0].type",
+			want: "center[0].type: missing widget type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil {
+				t.Fatalf("Parse() succeeded, want %q", tt.want)
+			}
+
+			if err.Error() != tt.want {
+				t.Errorf("Parse() error = %q, want %q", err, tt.want)
+			}
+
+			var cfgErr *Error
+			if tt.key != "" && (!errors.As(err, &cfgErr) || cfgErr.Key != tt.key) {
+				t.Errorf("Parse() error key = %v, want %q", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := Default()
+
+	if len(cfg.Left)+len(cfg.Center)+len(cfg.Right) == 0 {
+		t.Error("Default() has no widgets")
+	}
+}
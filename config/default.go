@@ -0,0 +1,32 @@
+package config
+
+// defaultConfig is the layout used when no configuration file exists.
+const defaultConfig = `{
+	"left": [
+		{"type": "workspace"},
+		{"type": "window"}
+	],
+	"center": [
+		{"type": "player"}
+	],
+	"right": [
+		{"type": "cpu"},
+		{"type": "memory"},
+		{"type": "disk", "path": "/"},
+		{"type": "network"},
+		{"type": "volume"},
+		{"type": "notification"},
+		{"type": "date"},
+		{"type": "clock"}
+	]
+}`
+
+// Default returns the built-in bar layout.
+func Default() *Config {
+	cfg, err := Parse([]byte(defaultConfig))
+	if err != nil {
+		panic("config: invalid default configuration: " + err.Error())
+	}
+
+	return cfg
+}
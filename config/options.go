@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Options holds the widget specific keys of a widget entry. Every getter
+// records the key it looked up so that misspelled or unsupported keys can be
+// reported by CheckUnused, and the options a widget supports can be listed
+// by Known.
+type Options struct {
+	path  string
+	raw   map[string]json.RawMessage
+	used  map[string]bool
+	known []Option
+}
+
+// Option describes an option a widget looked up.
+type Option struct {
+	Key string
+	// Kind is the type of the value: string, integer, boolean, duration or
+	// map.
+	Kind    string
+	Default string
+}
+
+// NewOptions returns an empty set of options, as used for widgets that are
+// created without configuration.
+func NewOptions() *Options {
+	return newOptions("", nil)
+}
+
+func newOptions(path string, raw map[string]json.RawMessage) *Options {
+	if raw == nil {
+		raw = make(map[string]json.RawMessage)
+	}
+
+	return &Options{
+		path: path,
+		raw:  raw,
+		used: make(map[string]bool),
+	}
+}
+
+// Path returns the location of the widget entry, e.g. "right[2]".
+func (o *Options) Path() string {
+	return o.path
+}
+
+// String returns the string value of key, or def if the key is not set.
+func (o *Options) String(key, def string) (string, error) {
+	var value string
+	ok, err := o.decode(key, &value, Option{Key: key, Kind: "string", Default: fmt.Sprintf("%q", def)}, "a string")
+	if err != nil || !ok {
+		return def, err
+	}
+
+	return value, nil
+}
+
+// Int returns the integer value of key, or def if the key is not set.
+func (o *Options) Int(key string, def int) (int, error) {
+	var value int
+	ok, err := o.decode(key, &value, Option{Key: key, Kind: "integer", Default: fmt.Sprint(def)}, "an integer")
+	if err != nil || !ok {
+		return def, err
+	}
+
+	return value, nil
+}
+
+// Bool returns the boolean value of key, or def if the key is not set.
+func (o *Options) Bool(key string, def bool) (bool, error) {
+	var value bool
+	ok, err := o.decode(key, &value, Option{Key: key, Kind: "boolean", Default: fmt.Sprint(def)}, "true or false")
+	if err != nil || !ok {
+		return def, err
+	}
+
+	return value, nil
+}
+
+// Duration returns the duration value of key, or def if the key is not set.
+// Durations are written as strings such as "500ms" or "2s".
+func (o *Options) Duration(key string, def time.Duration) (time.Duration, error) {
+	var value string
+	ok, err := o.decode(key, &value, Option{Key: key, Kind: "duration", Default: def.String()}, `a duration such as "2s"`)
+	if err != nil || !ok {
+		return def, err
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return def, o.Errorf(key, `expected a duration such as "2s", got %q`, value)
+	}
+
+	if d <= 0 {
+		return def, o.Errorf(key, "expected a positive duration, got %q", value)
+	}
+
+	return d, nil
+}
+
+// StringMap returns the value of key, an object with string values, or an
+// empty map if the key is not set.
+func (o *Options) StringMap(key string) (map[string]string, error) {
+	value := make(map[string]string)
+	ok, err := o.decode(key, &value, Option{Key: key, Kind: "map", Default: "{}"}, "an object with string values")
+	if err != nil || !ok {
+		return make(map[string]string), err
+	}
+
+	return value, nil
+}
+
+// CheckUnused returns an error naming the first key that was never looked
+// up, which usually is a typo in the configuration file.
+func (o *Options) CheckUnused() error {
+	var unused []string
+	for key := range o.raw {
+		if !o.used[key] {
+			unused = append(unused, key)
+		}
+	}
+
+	if len(unused) == 0 {
+		return nil
+	}
+
+	sort.Strings(unused)
+	return o.Errorf(unused[0], "unknown option")
+}
+
+// Known returns the options looked up so far, in lookup order.
+func (o *Options) Known() []Option {
+	return append([]Option(nil), o.known...)
+}
+
+func (o *Options) decode(key string, value any, option Option, expected string) (bool, error) {
+	if !o.used[key] {
+		o.known = append(o.known, option)
+	}
+	o.used[key] = true
+
+	raw, ok := o.raw[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, value); err != nil {
+		return false, o.Errorf(key, "expected %s, got %s", expected, raw)
+	}
+
+	return true, nil
+}
+
+// Errorf returns an *Error for key, qualified with the location of the
+// widget entry.
+func (o *Options) Errorf(key, format string, args ...any) error {
+	if o.path != "" {
+		key = o.path + "." + key
+	}
+
+	return &Error{Key: key, Err: fmt.Errorf(format, args...)}
+}
+
+func (o *Options) equal(other *Options) bool {
+	var a, b map[string]json.RawMessage
+	if o != nil {
+		a = o.raw
+	}
+	if other != nil {
+		b = other.raw
+	}
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, value := range a {
+		otherValue, ok := b[key]
+		if !ok || !jsonEqual(value, otherValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonEqual compares two JSON values ignoring insignificant whitespace.
+func jsonEqual(a, b json.RawMessage) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseOptions returns the options of a widget entry at left[0] holding
+// the keys of the JSON object options.
+func parseOptions(t *testing.T, options string) *Options {
+	t.Helper()
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(options), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	return newOptions("left[0]", raw)
+}
+
+func TestOptionsGetters(t *testing.T) {
+	opts := parseOptions(t, `{
+		"format": "%H:%M",
+		"width": 12,
+		"tooltip": true,
+		"interval": "500ms",
+		"labels": {"English (US)": "us"}
+	}`)
+
+	if got, err := opts.String("format", ""); err != nil || got != "%H:%M" {
+		t.Errorf("String() = %q, %v, want %q", got, err, "%H:%M")
+	}
+	if got, err := opts.String("missing", "def"); err != nil || got != "def" {
+		t.Errorf("String() of a missing key = %q, %v, want the default", got, err)
+	}
+	if got, err := opts.Int("width", 0); err != nil || got != 12 {
+		t.Errorf("Int() = %d, %v, want 12", got, err)
+	}
+	if got, err := opts.Bool("tooltip", false); err != nil || !got {
+		t.Errorf("Bool() = %t, %v, want true", got, err)
+	}
+	if got, err := opts.Duration("interval", time.Second); err != nil || got != 500*time.Millisecond {
+		t.Errorf("Duration() = %v, %v, want 500ms", got, err)
+	}
+	if got, err := opts.StringMap("labels"); err != nil || !reflect.DeepEqual(got, map[string]string{"English (US)": "us"}) {
+		t.Errorf("StringMap() = %v, %v", got, err)
+	}
+	if got, err := opts.StringMap("missing"); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("StringMap() of a missing key = %v, %v, want an empty map", got, err)
+	}
+
+	want := []Option{
+		{Key: "format", Kind: "string", Default: `""`},
+		{Key: "missing", Kind: "string", Default: `"def"`},
+		{Key: "width", Kind: "integer", Default: "0"},
+		{Key: "tooltip", Kind: "boolean", Default: "false"},
+		{Key: "interval", Kind: "duration", Default: "1s"},
+		{Key: "labels", Kind: "map", Default: "{}"},
+	}
+	if got := opts.Known(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Known() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		get     func(o *Options) error
+		want    string
+	}{
+		{
+			name:    "string",
+			options: `{"format": 3}`,
+			get: func(o *Options) error {
+				_, err := o.String("format", "")
+				return err
+			},
+			want: "left[0].format: expected a string, got 3",
+		},
+		{
+			name:    "integer",
+			options: `{"width": "wide"}`,
+			get: func(o *Options) error {
+				_, err := o.Int("width", 0)
+				return err
+			},
+			want: `left[0].width: expected an integer, got "wide"`,
+		},
+		{
+			name:    "boolean",
+			options: `{"tooltip": "yes"}`,
+			get: func(o *Options) error {
+				_, err := o.Bool("tooltip", false)
+				return err
+			},
+			want: `left[0].tooltip: expected true or false, got "yes"`,
+		},
+		{
+			name:    "duration type",
+			options: `{"interval": 5}`,
+			get: func(o *Options) error {
+				_, err := o.Duration("interval", time.Second)
+				return err
+			},
+			want: `left[0].interval: expected a duration such as "2s", got 5`,
+		},
+		{
+			name:    "duration syntax",
+			options: `{"interval": "5 seconds"}`,
+			get: func(o *Options) error {
+				_, err := o.Duration("interval", time.Second)
+				return err
+			},
+			want: `left[0].interval: expected a duration such as "2s", got "5 seconds"`,
+		},
+		{
+			name:    "negative duration",
+			options: `{"interval": "-1s"}`,
+			get: func(o *Options) error {
+				_, err := o.Duration("interval", time.Second)
+				return err
+			},
+			want: `left[0].interval: expected a positive duration, got "-1s"`,
+		},
+		{
+			name:    "map values",
+			options: `{"labels": {"us": 1}}`,
+			get: func(o *Options) error {
+				_, err := o.StringMap("labels")
+				return err
+			},
+			want: `left[0].labels: expected an object with string values, got {"us": 1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.get(parseOptions(t, tt.options))
+			if err == nil {
+				t.Fatalf("getter succeeded, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("getter error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsCheckUnused(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		used    []string
+		want    string
+	}{
+		{
+			name:    "every key used",
+			options: `{"format": "%H", "tooltip": true}`,
+			used:    []string{"format", "tooltip"},
+		},
+		{
+			name:    "unused key",
+			options: `{"format": "%H", "fromat": "%M"}`,
+			used:    []string{"format"},
+			want:    "left[0].fromat: unknown option",
+		},
+		{
+			name:    "first unused key",
+			options: `{"zoom": 1, "colour": "red", "format": "%H"}`,
+			used:    []string{"format"},
+			want:    "left[0].colour: unknown option",
+		},
+		{
+			name:    "looked up but not set",
+			options: `{}`,
+			used:    []string{"format"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := parseOptions(t, tt.options)
+			for _, key := range tt.used {
+				opts.String(key, "")
+			}
+
+			err := opts.CheckUnused()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("CheckUnused() error = %v, want nil", err)
+			case tt.want != "" && (err == nil || err.Error() != tt.want):
+				t.Errorf("CheckUnused() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOptionsPath(t *testing.T) {
+	err := NewOptions().Errorf("format", "bad")
+	if err.Error() != "format: bad" {
+		t.Errorf("Errorf() = %q, want %q", err, "format: bad")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/ipc"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/widgets"
+)
+
+// controlCommand is a command understood on the control socket. handle runs
+// on the GTK thread.
+type controlCommand struct {
+	usage  string
+	handle func(b *Bar, args []string) ipc.Response
+}
+
+var controlCommands map[string]controlCommand
+
+func init() {
+	controlCommands = map[string]controlCommand{
+		"show":      {"show", (*Bar).show},
+		"hide":      {"hide", (*Bar).hide},
+		"toggle":    {"toggle", (*Bar).toggle},
+		"reload":    {"reload [config|style]", (*Bar).reload},
+		"refresh":   {"refresh WIDGET", (*Bar).refresh},
+		"set-text":  {"set-text WIDGET TEXT", (*Bar).setText},
+		"set-class": {"set-class WIDGET [CLASS]", (*Bar).setClass},
+		"state":     {"state", (*Bar).state},
+	}
+}
+
+// controlUsage lists the commands of the control socket.
+func controlUsage() string {
+	var usages []string
+	for _, cmd := range controlCommands {
+		usages = append(usages, cmd.usage)
+	}
+	sort.Strings(usages)
+
+	return strings.Join(usages, ", ")
+}
+
+// serve answers requests on the control socket until the bar exits.
+func (b *Bar) serve() {
+	err := ipc.Listen(b.ctx, func(req ipc.Request) ipc.Response {
+		cmd, ok := controlCommands[req.Command]
+		if !ok {
+			return ipc.Errorf("unknown command %q, expected one of: %s", req.Command, controlUsage())
+		}
+
+		done := make(chan ipc.Response, 1)
+		glib.IdleAdd(func() {
+			done <- cmd.handle(b, req.Args)
+		})
+
+		select {
+		case resp := <-done:
+			return resp
+		case <-b.ctx.Done():
+			return ipc.Errorf("go-bar is exiting")
+		}
+	})
+	if err != nil {
+		libs.Log.Println("unable to serve control socket:", err)
+	}
+}
+
+func (b *Bar) show(args []string) ipc.Response {
+	b.window.Show()
+	return ipc.Result(nil)
+}
+
+func (b *Bar) hide(args []string) ipc.Response {
+	b.window.Hide()
+	return ipc.Result(nil)
+}
+
+func (b *Bar) toggle(args []string) ipc.Response {
+	if b.window.GetVisible() {
+		return b.hide(args)
+	}
+
+	return b.show(args)
+}
+
+// reload reads the configuration and the stylesheet again, or only the one
+// named in args.
+func (b *Bar) reload(args []string) ipc.Response {
+	what := ""
+	if len(args) > 0 {
+		what = args[0]
+	}
+
+	switch what {
+	case "", "config", "style":
+	default:
+		return ipc.Errorf("usage: %s", controlCommands["reload"].usage)
+	}
+
+	if what != "style" {
+		if err := b.reloadConfig(); err != nil {
+			return ipc.Errorf("keeping previous configuration: %v", err)
+		}
+	}
+
+	if what != "config" {
+		if err := b.loadStyle(); err != nil {
+			return ipc.Errorf("keeping previous stylesheet: %v", err)
+		}
+	}
+
+	return ipc.Result(nil)
+}
+
+// refresh polls the named widgets right away.
+func (b *Bar) refresh(args []string) ipc.Response {
+	if len(args) != 1 {
+		return ipc.Errorf("usage: %s", controlCommands["refresh"].usage)
+	}
+
+	entries, resp := b.lookup(args[0])
+	if entries == nil {
+		return resp
+	}
+
+	refreshed := false
+	for _, e := range entries {
+		if e.job != nil {
+			e.job.Refresh()
+			refreshed = true
+		}
+	}
+
+	if !refreshed {
+		return ipc.Errorf("widget %q does not poll", args[0])
+	}
+
+	return ipc.Result(nil)
+}
+
+// setText replaces the text of the named widgets.
+func (b *Bar) setText(args []string) ipc.Response {
+	if len(args) < 1 {
+		return ipc.Errorf("usage: %s", controlCommands["set-text"].usage)
+	}
+
+	entries, resp := b.lookup(args[0])
+	if entries == nil {
+		return resp
+	}
+
+	// Either every widget gets the text or none does
+	setters := make([]widgets.TextSetter, 0, len(entries))
+	for _, e := range entries {
+		setter, ok := e.widget.(widgets.TextSetter)
+		if !ok {
+			return ipc.Errorf("the text of %s widgets cannot be set", e.widget.Name())
+		}
+		setters = append(setters, setter)
+	}
+
+	text := strings.Join(args[1:], " ")
+	for _, setter := range setters {
+		setter.SetText(text)
+	}
+
+	return ipc.Result(nil)
+}
+
+// setClass replaces the CSS class previously set on the named widgets, or
+// removes it when no class is given.
+func (b *Bar) setClass(args []string) ipc.Response {
+	if len(args) < 1 || len(args) > 2 {
+		return ipc.Errorf("usage: %s", controlCommands["set-class"].usage)
+	}
+
+	entries, resp := b.lookup(args[0])
+	if entries == nil {
+		return resp
+	}
+
+	class := ""
+	if len(args) == 2 {
+		class = args[1]
+	}
+
+	for _, e := range entries {
+		styleContext, err := e.box().GetStyleContext()
+		if err != nil {
+			return ipc.Errorf("unable to get style context: %v", err)
+		}
+
+		if e.class != "" {
+			styleContext.RemoveClass(e.class)
+		}
+		if class != "" {
+			styleContext.AddClass(class)
+		}
+		e.class = class
+	}
+
+	return ipc.Result(nil)
+}
+
+// barState is the answer to the state command.
+type barState struct {
+	Visible    bool          `json:"visible"`
+	Compositor string        `json:"compositor"`
+	Hyprland   bool          `json:"hyprland"` // connected to Hyprland events
+	Widgets    []widgetState `json:"widgets"`
+}
+
+type widgetState struct {
+	Section string `json:"section"`
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Running bool   `json:"running"`
+	Error   string `json:"error,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+func (b *Bar) state(args []string) ipc.Response {
+	state := barState{
+		Visible:    b.window.GetVisible(),
+		Compositor: b.compositor.Name(),
+		Hyprland:   b.events.Connected(),
+		Widgets:    []widgetState{},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, s := range b.sections {
+		for _, e := range s.entries {
+			ws := widgetState{
+				Section: config.SectionNames[i],
+				Type:    e.config.Type,
+				ID:      e.config.ID,
+				Running: e.running,
+				Error:   widgets.ErrorText(e.box()),
+				Class:   e.class,
+			}
+
+			if setter, ok := e.widget.(widgets.TextSetter); ok {
+				ws.Text = setter.Text()
+			}
+
+			state.Widgets = append(state.Widgets, ws)
+		}
+	}
+
+	return ipc.Result(state)
+}
+
+// lookup returns the entries whose ID, or else whose type, is name. If there
+// are none, it returns the error response to send instead.
+func (b *Bar) lookup(name string) ([]*entry, ipc.Response) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var byID, byType []*entry
+	for _, s := range b.sections {
+		for _, e := range s.entries {
+			if e.config.ID == name {
+				byID = append(byID, e)
+			} else if e.config.Type == name {
+				byType = append(byType, e)
+			}
+		}
+	}
+
+	if byID != nil {
+		return byID, ipc.Response{}
+	}
+	if byType != nil {
+		return byType, ipc.Response{}
+	}
+
+	return nil, ipc.Errorf("no widget called %q", name)
+}
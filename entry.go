@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/scheduler"
+	"github.com/grentenrg/go-bar/widgets"
+)
+
+// entry is a widget of the bar together with the configuration it was built
+// from.
+type entry struct {
+	config  config.WidgetConfig
+	widget  widgets.Widget
+	section *section
+	job     *scheduler.Handle  // set for running widgets implementing widgets.Poller
+	events  *libs.Subscription // set for running widgets implementing widgets.EventHandler
+	unwatch func()             // set for running widgets implementing widgets.CompositorWatcher
+	class   string             // CSS class set with "go-bar msg set-class"
+
+	// While the widget cannot be created or started, a placeholder in its
+	// error state takes its place and activation is retried with backoff.
+	running     bool
+	placeholder *gtk.Box
+	backoff     libs.Backoff
+	retry       glib.SourceHandle
+}
+
+func newEntry(wc config.WidgetConfig, widget widgets.Widget, s *section) *entry {
+	return &entry{
+		config:  wc,
+		widget:  widget,
+		section: s,
+		backoff: libs.Backoff{Min: time.Second, Max: time.Minute},
+	}
+}
+
+// box returns the box shown in the bar for the entry.
+func (e *entry) box() *gtk.Box {
+	if e.running {
+		return e.widget.Box()
+	}
+
+	return e.placeholder
+}
+
+// activate creates and starts the widget of an entry and schedules its
+// polling. On failure the entry shows its placeholder and activation is
+// tried again later.
+func (b *Bar) activate(e *entry) {
+	err := e.widget.Create()
+	if err != nil {
+		// Release whatever Create built before it failed
+		if box := e.widget.Box(); box != nil {
+			box.Destroy()
+		}
+	} else {
+		if setter, ok := e.widget.(widgets.CompositorSetter); ok {
+			setter.SetCompositor(b.compositor.Name())
+		}
+		if err = e.widget.Start(b.ctx); err != nil {
+			e.widget.Destroy()
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("unable to create %s widget: %w", e.widget.Name(), err)
+		libs.Log.Println(err)
+
+		if e.placeholder == nil {
+			placeholder, boxErr := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+			if boxErr != nil {
+				log.Fatal("Unable to create box:", boxErr)
+			}
+			e.placeholder = placeholder
+		}
+		widgets.ShowError(e.placeholder, err)
+
+		e.retry = glib.TimeoutAdd(uint(e.backoff.Next().Milliseconds()), func() bool {
+			e.retry = 0
+			b.activate(e)
+			return false
+		})
+		return
+	}
+
+	e.running = true
+	e.backoff.Reset()
+
+	if poller, ok := e.widget.(widgets.Poller); ok {
+		box := e.widget.Box()
+		e.job = b.scheduler.Add(b.ctx, scheduler.Job{
+			Name:     e.widget.Name(),
+			Interval: poller.Interval(),
+			Run:      poller.Poll,
+			Report: func(err error) {
+				if err != nil {
+					widgets.ShowError(box, err)
+				} else {
+					widgets.ClearError(box)
+				}
+			},
+		})
+	}
+
+	// The event bus follows Hyprland, which is not running otherwise
+	handler, ok := e.widget.(widgets.EventHandler)
+	if ok && b.compositor.Name() == compositor.KindHyprland {
+		box := e.widget.Box()
+		e.events = b.events.Subscribe(libs.Subscriber{
+			Events: handler.Events(),
+			Handle: handler.HandleEvent,
+			Resync: handler.Resync,
+			Report: func(err error) {
+				if err != nil {
+					widgets.ShowError(box, err)
+				} else {
+					widgets.ClearError(box)
+				}
+			},
+		})
+	}
+
+	if watcher, ok := e.widget.(widgets.CompositorWatcher); ok {
+		box := e.widget.Box()
+		e.unwatch = b.compositor.Watch(compositor.Watcher{
+			Changed: func() {
+				watcher.CompositorChanged(b.compositor, b.output())
+			},
+			Report: func(err error) {
+				if err != nil {
+					widgets.ShowError(box, err)
+				} else {
+					widgets.ClearError(box)
+				}
+			},
+		})
+	}
+
+	// Retried activations replace the placeholder that is already packed
+	if placeholder := e.placeholder; placeholder != nil {
+		e.placeholder = nil
+		e.section.replace(e, placeholder)
+	}
+}
+
+// destroy cancels a pending activation, unschedules the widget and
+// destroys it.
+func (e *entry) destroy() {
+	if e.retry != 0 {
+		glib.SourceRemove(e.retry)
+		e.retry = 0
+	}
+
+	if e.job != nil {
+		e.job.Remove()
+	}
+
+	if e.events != nil {
+		e.events.Unsubscribe()
+	}
+
+	if e.unwatch != nil {
+		e.unwatch()
+	}
+
+	if e.running {
+		e.widget.Destroy()
+	}
+
+	if e.placeholder != nil {
+		e.placeholder.Destroy()
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/widgets"
+)
+
+var gtkInit struct {
+	once sync.Once
+	err  error
+}
+
+// requireGTK initializes GTK, skipping the test where there is no display.
+func requireGTK(t *testing.T) {
+	t.Helper()
+
+	gtkInit.once.Do(func() {
+		gtkInit.err = gtk.InitCheck(nil)
+	})
+	if gtkInit.err != nil {
+		t.Skip("GTK unavailable:", gtkInit.err)
+	}
+}
+
+// iterate runs the GTK main loop for d.
+func iterate(d time.Duration) {
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		for gtk.EventsPending() {
+			gtk.MainIterationDo(false)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// failingWidget builds its box and then fails to create the rest.
+type failingWidget struct {
+	widgets.Lifecycle
+	box          *gtk.Box
+	creates      int
+	boxDestroyed int
+}
+
+func (w *failingWidget) Create() error {
+	w.creates++
+
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		return err
+	}
+	box.Connect("destroy", func() {
+		w.boxDestroyed++
+	})
+	w.box = box
+
+	return errors.New("device not found")
+}
+
+func (w *failingWidget) Start(ctx context.Context) error {
+	return nil
+}
+
+func (w *failingWidget) Destroy() {
+	w.Stop()
+	w.box.Destroy()
+}
+
+func (w *failingWidget) Name() string {
+	return "failing"
+}
+
+func (w *failingWidget) Box() *gtk.Box {
+	return w.box
+}
+
+// newTestBar returns a bar with one section holding an entry for widget,
+// which is retried every few milliseconds.
+func newTestBar(t *testing.T, widget widgets.Widget) (*Bar, *entry) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bar{
+		ctx: ctx,
+		compositor: compositor.NewNone(func(f func()) {
+			glib.IdleAdd(f)
+		}),
+	}
+
+	s := &section{box: box}
+	e := newEntry(config.WidgetConfig{Type: widget.Name()}, widget, s)
+	e.backoff = libs.Backoff{Min: 10 * time.Millisecond, Max: 10 * time.Millisecond}
+	s.entries = []*entry{e}
+	b.sections = []*section{s}
+
+	return b, e
+}
+
+func TestEntryRetry(t *testing.T) {
+	requireGTK(t)
+
+	w := &failingWidget{}
+	b, e := newTestBar(t, w)
+
+	b.activate(e)
+	if e.running || e.placeholder == nil || e.retry == 0 {
+		t.Fatalf("failed activation: running %t, placeholder %v, retry %d", e.running, e.placeholder, e.retry)
+	}
+
+	iterate(200 * time.Millisecond)
+	if w.creates < 2 {
+		t.Errorf("Create called %d times, want retries", w.creates)
+	}
+
+	// Every box built by a failed Create is released
+	if w.boxDestroyed != w.creates {
+		t.Errorf("%d of %d boxes destroyed", w.boxDestroyed, w.creates)
+	}
+
+	b.destroyWidgets()
+}
+
+func TestEntryRetryCancelled(t *testing.T) {
+	requireGTK(t)
+
+	w := &failingWidget{}
+	b, e := newTestBar(t, w)
+
+	b.activate(e)
+
+	var placeholderDestroyed bool
+	e.placeholder.Connect("destroy", func() {
+		placeholderDestroyed = true
+	})
+
+	b.destroyWidgets()
+	iterate(200 * time.Millisecond)
+
+	if w.creates != 1 {
+		t.Errorf("Create called %d times after the entry was destroyed, want 1", w.creates)
+	}
+	if e.retry != 0 {
+		t.Error("retry still scheduled")
+	}
+	if !placeholderDestroyed {
+		t.Error("placeholder not destroyed")
+	}
+	if len(b.sections[0].entries) != 0 {
+		t.Errorf("section still has %d entries", len(b.sections[0].entries))
+	}
+}
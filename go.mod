@@ -4,4 +4,4 @@ go 1.23.6
 
 require github.com/gotk3/gotk3 v0.6.5-0.20240618185848-ff349ae13f56
 
-require github.com/dlasky/gotk3-layershell v0.0.0-20240515133811-5c5115f0d774 // indirect
+require github.com/dlasky/gotk3-layershell v0.0.0-20240515133811-5c5115f0d774
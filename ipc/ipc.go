@@ -0,0 +1,85 @@
+// Package ipc implements the protocol used to control a running go-bar
+// instance over a unix socket. A client writes one JSON encoded Request per
+// line and reads one JSON encoded Response per line back.
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// socketName is the name of the control socket in $XDG_RUNTIME_DIR.
+const socketName = "go-bar.sock"
+
+// Request is a command sent to a running instance, e.g. "refresh" with the
+// arguments ["cpu"].
+type Request struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Response is the answer of a running instance to a Request.
+type Response struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// SocketPath returns the path of the control socket.
+func SocketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errors.New("XDG_RUNTIME_DIR environment variable not set")
+	}
+
+	return filepath.Join(dir, socketName), nil
+}
+
+// Send sends req to the running instance and waits for its response.
+func Send(ctx context.Context, req Request) (*Response, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to go-bar, is it running? %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,202 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRuntimeDir points $XDG_RUNTIME_DIR at a new directory for the rest of
+// the test and returns the path of the control socket in it.
+func setRuntimeDir(t *testing.T) string {
+	t.Helper()
+
+	// Socket paths are limited to about a hundred bytes, which the
+	// directories of t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	return filepath.Join(dir, socketName)
+}
+
+// startListen runs Listen with handle until the test ends and waits for
+// the socket to accept connections.
+func startListen(t *testing.T, path string, handle Handler) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Listen(ctx, handle)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Listen() error = %v", err)
+		}
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket not listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// echo answers requests with their arguments, if any, failing "fail"
+// requests.
+func echo(req Request) Response {
+	if req.Command == "fail" {
+		return Errorf("%s failed", req.Command)
+	}
+	if len(req.Args) == 0 {
+		return Result(nil)
+	}
+
+	return Result(req.Args)
+}
+
+func TestSendRoundTrip(t *testing.T) {
+	path := setRuntimeDir(t)
+	startListen(t, path, echo)
+
+	resp, err := Send(context.Background(), Request{Command: "set-text", Args: []string{"status", "hello world"}})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !resp.OK || resp.Error != "" {
+		t.Fatalf("Send() = %+v, want a successful response", resp)
+	}
+
+	var args []string
+	if err := json.Unmarshal(resp.Data, &args); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, "|") != "status|hello world" {
+		t.Errorf("Send() data = %q, want the arguments", args)
+	}
+
+	resp, err = Send(context.Background(), Request{Command: "fail"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if resp.OK || resp.Error != "fail failed" {
+		t.Errorf("Send() = %+v, want the error of the handler", resp)
+	}
+}
+
+func TestListenFraming(t *testing.T) {
+	path := setRuntimeDir(t)
+	startListen(t, path, echo)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Several requests in one write, one of them invalid, are answered one
+	// line each and in order
+	requests := `{"command":"a","args":["1"]}` + "\n" + `not json` + "\n" + `{"command":"b"}` + "\n"
+	if _, err := conn.Write([]byte(requests)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	want := []string{
+		`{"ok":true,"data":["1"]}`,
+		`{"ok":false,"error":"invalid request: invalid character 'o' in literal null (expecting 'u')"}`,
+		`{"ok":true}`,
+	}
+	for i, w := range want {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+		if got := strings.TrimSuffix(line, "\n"); got != w {
+			t.Errorf("response %d = %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestListenSocket(t *testing.T) {
+	path := setRuntimeDir(t)
+
+	// A socket left behind by a crashed instance
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	startListen(t, path, echo)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	// A second instance must not take over the socket
+	if err := Listen(context.Background(), echo); err == nil {
+		t.Error("second Listen() succeeded, want an error")
+	}
+}
+
+func TestListenRemovesSocket(t *testing.T) {
+	path := setRuntimeDir(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Listen(ctx, echo)
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket not created")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after Listen() returned: %v", err)
+	}
+}
+
+func TestSendNotRunning(t *testing.T) {
+	setRuntimeDir(t)
+
+	if _, err := Send(context.Background(), Request{Command: "state"}); err == nil {
+		t.Error("Send() succeeded without a running instance")
+	}
+}
@@ -0,0 +1,120 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// Handler answers a single request.
+type Handler func(req Request) Response
+
+// Result returns a successful response carrying data, which is encoded as
+// JSON unless it is nil.
+func Result(data any) Response {
+	if data == nil {
+		return Response{OK: true}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Errorf("unable to encode result: %v", err)
+	}
+
+	return Response{OK: true, Data: raw}
+}
+
+// Errorf returns a failed response.
+func Errorf(format string, args ...any) Response {
+	return Response{Error: fmt.Sprintf(format, args...)}
+}
+
+// Listen serves requests on the control socket until ctx is done. Each
+// request is answered by handle, which may be called concurrently. A socket
+// left behind by an instance that is gone is replaced, but Listen fails if
+// another instance is still serving it.
+func Listen(ctx context.Context, handle Handler) error {
+	path, err := SocketPath()
+	if err != nil {
+		return err
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("another instance is listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove stale socket: %w", err)
+	}
+
+	var config net.ListenConfig
+	listener, err := config.Listen(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", path, err)
+	}
+	defer os.Remove(path)
+
+	// Only the user running the bar may control it
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("unable to restrict socket permissions: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Unblock Accept and the open connections when the bar exits
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("unable to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(ctx, conn, handle)
+		}()
+	}
+}
+
+// serve answers the requests of a connection, one per line.
+func serve(ctx context.Context, conn net.Conn, handle Handler) {
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var resp Response
+
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Errorf("invalid request: %v", err)
+		} else {
+			resp = handle(req)
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
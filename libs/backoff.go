@@ -0,0 +1,29 @@
+package libs
+
+import "time"
+
+// Backoff computes exponentially growing delays between retries.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	next time.Duration
+}
+
+// Next returns the delay before the next attempt: Min at first, then twice
+// the previous delay, up to Max.
+func (b *Backoff) Next() time.Duration {
+	if b.next == 0 {
+		b.next = b.Min
+	}
+
+	delay := b.next
+	b.next = min(2*b.next, b.Max)
+
+	return delay
+}
+
+// Reset starts over from Min after a successful attempt.
+func (b *Backoff) Reset() {
+	b.next = 0
+}
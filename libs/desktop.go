@@ -0,0 +1,151 @@
+package libs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// desktopIcons indexes the icons of the installed desktop entries by the
+// names windows are known under. It is built in the background on first
+// use; applications installed later are found after a restart.
+var desktopIcons struct {
+	once  sync.Once
+	ready chan struct{}     // closed once icons is set
+	icons map[string]string // keyed by lower-cased name
+}
+
+// LoadDesktopIcons starts indexing the desktop entries unless it was
+// started already, and returns a channel closed once DesktopIcon finds
+// their icons.
+func LoadDesktopIcons() <-chan struct{} {
+	desktopIcons.once.Do(func() {
+		desktopIcons.ready = make(chan struct{})
+		go func() {
+			desktopIcons.icons = indexDesktopEntries()
+			close(desktopIcons.ready)
+		}()
+	})
+
+	return desktopIcons.ready
+}
+
+// DesktopIcon returns the icon named by the desktop entry of an
+// application, found by its app ID or window class, or "" if there is none
+// or the entries are still being indexed. Entries are matched by file name,
+// by the last part of reverse DNS file names such as
+// org.gnome.Nautilus.desktop, and by StartupWMClass. The icon is either an
+// icon name or an absolute path.
+func DesktopIcon(appID string) string {
+	select {
+	case <-LoadDesktopIcons():
+		return desktopIcons.icons[strings.ToLower(appID)]
+	default:
+		return ""
+	}
+}
+
+// desktopDataDirs returns the XDG data directories, most important first.
+func desktopDataDirs() []string {
+	home := os.Getenv("XDG_DATA_HOME")
+	if home == "" {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".local", "share")
+		}
+	}
+
+	dirs := os.Getenv("XDG_DATA_DIRS")
+	if dirs == "" {
+		dirs = "/usr/local/share:/usr/share"
+	}
+
+	var result []string
+	for _, dir := range append([]string{home}, filepath.SplitList(dirs)...) {
+		// Relative paths are invalid per the XDG specification
+		if filepath.IsAbs(dir) {
+			result = append(result, dir)
+		}
+	}
+
+	return result
+}
+
+func indexDesktopEntries() map[string]string {
+	icons := make(map[string]string)
+
+	// Earlier directories win, as do file names over the weaker matches
+	var weak []map[string]string
+	for _, dir := range desktopDataDirs() {
+		paths, _ := filepath.Glob(filepath.Join(dir, "applications", "*.desktop"))
+		for _, path := range paths {
+			icon, class := readDesktopEntry(path)
+			if icon == "" {
+				continue
+			}
+
+			id := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".desktop"))
+			if _, ok := icons[id]; !ok {
+				icons[id] = icon
+			}
+
+			names := make(map[string]string)
+			if class != "" {
+				names[strings.ToLower(class)] = icon
+			}
+			if i := strings.LastIndex(id, "."); i >= 0 {
+				names[id[i+1:]] = icon
+			}
+			weak = append(weak, names)
+		}
+	}
+
+	for _, names := range weak {
+		for name, icon := range names {
+			if _, ok := icons[name]; !ok {
+				icons[name] = icon
+			}
+		}
+	}
+
+	return icons
+}
+
+// readDesktopEntry returns the Icon and StartupWMClass keys of the main
+// group of a desktop entry.
+func readDesktopEntry(path string) (icon, class string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	inMain := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") {
+			inMain = line == "[Desktop Entry]"
+			continue
+		}
+		if !inMain {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "Icon":
+			icon = strings.TrimSpace(value)
+		case "StartupWMClass":
+			class = strings.TrimSpace(value)
+		}
+	}
+
+	return icon, class
+}
@@ -0,0 +1,58 @@
+package libs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDesktopIcon(t *testing.T) {
+	home := t.TempDir()
+	system := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", home)
+	t.Setenv("XDG_DATA_DIRS", system)
+
+	writeEntry := func(dir, name, content string) {
+		t.Helper()
+
+		path := filepath.Join(dir, "applications", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeEntry(home, "firefox.desktop", "[Desktop Entry]\nIcon=firefox-custom\n")
+	writeEntry(system, "firefox.desktop", "[Desktop Entry]\nIcon=firefox\n")
+	writeEntry(system, "org.gnome.Nautilus.desktop", "[Desktop Entry]\nIcon=org.gnome.Nautilus\n")
+	writeEntry(system, "code.desktop", "[Desktop Entry]\nIcon=/opt/code/code.png\nStartupWMClass=Code-OSS\n\n[Desktop Action new]\nIcon=other\n")
+	writeEntry(system, "noicon.desktop", "[Desktop Entry]\nName=No icon\n")
+
+	select {
+	case <-LoadDesktopIcons():
+	case <-time.After(5 * time.Second):
+		t.Fatal("desktop entries not indexed")
+	}
+
+	tests := []struct {
+		appID string
+		want  string
+	}{
+		{"firefox", "firefox-custom"},
+		{"Firefox", "firefox-custom"},
+		{"nautilus", "org.gnome.Nautilus"},
+		{"org.gnome.Nautilus", "org.gnome.Nautilus"},
+		{"code-oss", "/opt/code/code.png"},
+		{"noicon", ""},
+		{"unknown", ""},
+	}
+
+	for _, tt := range tests {
+		if got := DesktopIcon(tt.appID); got != tt.want {
+			t.Errorf("DesktopIcon(%q) = %q, want %q", tt.appID, got, tt.want)
+		}
+	}
+}
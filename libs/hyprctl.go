@@ -0,0 +1,377 @@
+package libs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Hyprctl sends requests to Hyprland's request socket, like the hyprctl
+// command does, without starting a process per request.
+type Hyprctl struct {
+	// Timeout bounds requests whose context has no earlier deadline.
+	Timeout time.Duration
+}
+
+// defaultHyprctlTimeout is the timeout of requests made by NewHyprctl.
+const defaultHyprctlTimeout = 2 * time.Second
+
+// batchSeparator separates the replies to the commands of a batch.
+const batchSeparator = "\n\n\n"
+
+func NewHyprctl() *Hyprctl {
+	return &Hyprctl{Timeout: defaultHyprctlTimeout}
+}
+
+// WorkspaceRef identifies a workspace inside other replies.
+type WorkspaceRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Monitor is an entry of the monitors query.
+type Monitor struct {
+	ID               int          `json:"id"`
+	Name             string       `json:"name"`
+	Description      string       `json:"description"`
+	Make             string       `json:"make"`
+	Model            string       `json:"model"`
+	Width            int          `json:"width"`
+	Height           int          `json:"height"`
+	RefreshRate      float64      `json:"refreshRate"`
+	X                int          `json:"x"`
+	Y                int          `json:"y"`
+	ActiveWorkspace  WorkspaceRef `json:"activeWorkspace"`
+	SpecialWorkspace WorkspaceRef `json:"specialWorkspace"`
+	Scale            float64      `json:"scale"`
+	Transform        int          `json:"transform"`
+	Focused          bool         `json:"focused"`
+	DPMSStatus       bool         `json:"dpmsStatus"`
+	Disabled         bool         `json:"disabled"`
+}
+
+// Workspace is an entry of the workspaces query and the reply to the
+// activeworkspace query.
+type Workspace struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Monitor         string `json:"monitor"`
+	MonitorID       int    `json:"monitorID"`
+	Windows         int    `json:"windows"`
+	HasFullscreen   bool   `json:"hasfullscreen"`
+	LastWindow      string `json:"lastwindow"`
+	LastWindowTitle string `json:"lastwindowtitle"`
+}
+
+// Client is a window, as listed by the clients query and returned by the
+// activewindow query.
+type Client struct {
+	Address        string       `json:"address"`
+	Mapped         bool         `json:"mapped"`
+	Hidden         bool         `json:"hidden"`
+	At             [2]int       `json:"at"`
+	Size           [2]int       `json:"size"`
+	Workspace      WorkspaceRef `json:"workspace"`
+	Floating       bool         `json:"floating"`
+	Pinned         bool         `json:"pinned"`
+	Monitor        int          `json:"monitor"`
+	Class          string       `json:"class"`
+	Title          string       `json:"title"`
+	InitialClass   string       `json:"initialClass"`
+	InitialTitle   string       `json:"initialTitle"`
+	PID            int          `json:"pid"`
+	XWayland       bool         `json:"xwayland"`
+	FocusHistoryID int          `json:"focusHistoryID"`
+}
+
+// Devices is the reply to the devices query.
+type Devices struct {
+	Mice      []Mouse    `json:"mice"`
+	Keyboards []Keyboard `json:"keyboards"`
+	Tablets   []Device   `json:"tablets"`
+	Touch     []Device   `json:"touch"`
+	Switches  []Device   `json:"switches"`
+}
+
+// Device is an input device without type specific details.
+type Device struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+type Mouse struct {
+	Address      string  `json:"address"`
+	Name         string  `json:"name"`
+	DefaultSpeed float64 `json:"defaultSpeed"`
+}
+
+type Keyboard struct {
+	Address      string `json:"address"`
+	Name         string `json:"name"`
+	Rules        string `json:"rules"`
+	Model        string `json:"model"`
+	Layout       string `json:"layout"`
+	Variant      string `json:"variant"`
+	Options      string `json:"options"`
+	ActiveKeymap string `json:"active_keymap"`
+	CapsLock     bool   `json:"capsLock"`
+	NumLock      bool   `json:"numLock"`
+	Main         bool   `json:"main"`
+}
+
+// Layers is the reply to the layers query: the layer surfaces of each
+// monitor, keyed by monitor name.
+type Layers map[string]struct {
+	// Levels holds the surfaces of each layer, keyed "0" (background) to
+	// "3" (overlay).
+	Levels map[string][]Layer `json:"levels"`
+}
+
+type Layer struct {
+	Address   string `json:"address"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Width     int    `json:"w"`
+	Height    int    `json:"h"`
+	Namespace string `json:"namespace"`
+}
+
+// Bind is an entry of the binds query.
+type Bind struct {
+	Locked         bool   `json:"locked"`
+	Mouse          bool   `json:"mouse"`
+	Release        bool   `json:"release"`
+	Repeat         bool   `json:"repeat"`
+	NonConsuming   bool   `json:"non_consuming"`
+	HasDescription bool   `json:"has_description"`
+	Modmask        int    `json:"modmask"`
+	Submap         string `json:"submap"`
+	Key            string `json:"key"`
+	Keycode        int    `json:"keycode"`
+	CatchAll       bool   `json:"catch_all"`
+	Description    string `json:"description"`
+	Dispatcher     string `json:"dispatcher"`
+	Arg            string `json:"arg"`
+}
+
+// Modifier names of the bits of Bind.Modmask, lowest bit first.
+var modifierNames = []string{"SHIFT", "CAPS", "CTRL", "ALT", "MOD2", "MOD3", "SUPER", "MOD5"}
+
+// Keys returns the key combination of the bind, e.g. "SUPER+SHIFT+R".
+func (b Bind) Keys() string {
+	var keys []string
+	for _, i := range []int{6, 2, 3, 0, 1, 4, 5, 7} {
+		if b.Modmask&(1<<i) != 0 {
+			keys = append(keys, modifierNames[i])
+		}
+	}
+
+	key := b.Key
+	if key == "" && b.Keycode != 0 {
+		key = fmt.Sprintf("code:%d", b.Keycode)
+	}
+	if b.CatchAll {
+		key = "any key"
+	}
+
+	return strings.Join(append(keys, key), "+")
+}
+
+// Monitors lists the monitors.
+func (h *Hyprctl) Monitors(ctx context.Context) ([]Monitor, error) {
+	var monitors []Monitor
+	return monitors, h.query(ctx, "monitors", &monitors)
+}
+
+// Workspaces lists the workspaces.
+func (h *Hyprctl) Workspaces(ctx context.Context) ([]Workspace, error) {
+	var workspaces []Workspace
+	return workspaces, h.query(ctx, "workspaces", &workspaces)
+}
+
+// Clients lists the windows.
+func (h *Hyprctl) Clients(ctx context.Context) ([]Client, error) {
+	var clients []Client
+	return clients, h.query(ctx, "clients", &clients)
+}
+
+// ActiveWindow returns the focused window, or nil if no window has focus.
+func (h *Hyprctl) ActiveWindow(ctx context.Context) (*Client, error) {
+	var client Client
+	if err := h.query(ctx, "activewindow", &client); err != nil {
+		return nil, err
+	}
+
+	// Hyprland replies with an empty object when nothing has focus
+	if client.Address == "" {
+		return nil, nil
+	}
+
+	return &client, nil
+}
+
+// ActiveWorkspace returns the focused workspace.
+func (h *Hyprctl) ActiveWorkspace(ctx context.Context) (*Workspace, error) {
+	var workspace Workspace
+	return &workspace, h.query(ctx, "activeworkspace", &workspace)
+}
+
+// Devices lists the input devices.
+func (h *Hyprctl) Devices(ctx context.Context) (*Devices, error) {
+	var devices Devices
+	return &devices, h.query(ctx, "devices", &devices)
+}
+
+// Layers lists the layer surfaces.
+func (h *Hyprctl) Layers(ctx context.Context) (Layers, error) {
+	var layers Layers
+	return layers, h.query(ctx, "layers", &layers)
+}
+
+// Binds lists the key bindings of every submap.
+func (h *Hyprctl) Binds(ctx context.Context) ([]Bind, error) {
+	var binds []Bind
+	return binds, h.query(ctx, "binds", &binds)
+}
+
+// Submap returns the active submap, or "" for the default one.
+func (h *Hyprctl) Submap(ctx context.Context) (string, error) {
+	reply, err := h.Request(ctx, "submap")
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(string(reply))
+	if name == "unknown request" {
+		return "", errors.New("submap request not supported by this Hyprland version")
+	}
+	if name == "default" {
+		return "", nil
+	}
+
+	return name, nil
+}
+
+// Dispatch runs a dispatcher, e.g. Dispatch(ctx, "workspace", "2").
+func (h *Hyprctl) Dispatch(ctx context.Context, dispatcher string, args ...string) error {
+	return h.command(ctx, "dispatch "+join(dispatcher, args))
+}
+
+// Keyword sets a configuration value, e.g. Keyword(ctx,
+// "general:gaps_in", "5").
+func (h *Hyprctl) Keyword(ctx context.Context, keyword, value string) error {
+	return h.command(ctx, "keyword "+keyword+" "+value)
+}
+
+// Batch sends several commands, e.g. "dispatch workspace 2", in a single
+// request and returns the reply to each of them.
+func (h *Hyprctl) Batch(ctx context.Context, commands ...string) ([]string, error) {
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	reply, err := h.Request(ctx, "[[BATCH]]"+strings.Join(commands, ";"))
+	if err != nil {
+		return nil, err
+	}
+
+	replies := strings.Split(string(reply), batchSeparator)
+	if len(replies) != len(commands) {
+		return nil, fmt.Errorf("expected %d replies to batch, got %d", len(commands), len(replies))
+	}
+
+	return replies, nil
+}
+
+// Request sends a raw request, e.g. "j/monitors", and returns the reply.
+func (h *Hyprctl) Request(ctx context.Context, request string) ([]byte, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	socketPath, err := hyprlandSocket(".socket.sock")
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Hyprland socket: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Unblock reads and writes when the caller gives up early
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		return nil, h.requestError(ctx, request, err)
+	}
+
+	// Hyprland closes the connection once the reply is written
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, h.requestError(ctx, request, err)
+	}
+
+	return reply, nil
+}
+
+// query runs a JSON query and decodes the reply into v.
+func (h *Hyprctl) query(ctx context.Context, query string, v any) error {
+	reply, err := h.Request(ctx, "j/"+query)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(reply, v); err != nil {
+		return fmt.Errorf("unable to parse %s reply %q: %w", query, bytes.TrimSpace(reply), err)
+	}
+
+	return nil
+}
+
+// command runs a command replying "ok" on success and an error message
+// otherwise.
+func (h *Hyprctl) command(ctx context.Context, command string) error {
+	reply, err := h.Request(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	if text := strings.TrimSpace(string(reply)); text != "ok" {
+		return fmt.Errorf("%s: %s", command, text)
+	}
+
+	return nil
+}
+
+// requestError prefers the context error over the network error it caused.
+func (h *Hyprctl) requestError(ctx context.Context, request string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	} else if errors.Is(err, net.ErrClosed) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return fmt.Errorf("request %q to Hyprland failed: %w", request, err)
+}
+
+func join(first string, rest []string) string {
+	return strings.Join(append([]string{first}, rest...), " ")
+}
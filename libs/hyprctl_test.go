@@ -0,0 +1,94 @@
+package libs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/grentenrg/go-bar/libs/hyprtest"
+)
+
+func TestHyprctlDispatch(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	srv.SetReply("dispatch exec missing", "No such file or directory\n")
+
+	h := NewHyprctl()
+	if err := h.Dispatch(context.Background(), "workspace", "2"); err != nil {
+		t.Errorf("Dispatch() error = %v", err)
+	}
+
+	err := h.Dispatch(context.Background(), "exec", "missing")
+	if err == nil || err.Error() != "dispatch exec missing: No such file or directory" {
+		t.Errorf("Dispatch() error = %v, want the reply of Hyprland", err)
+	}
+
+	if got, want := srv.Dispatches(), []string{"workspace 2", "exec missing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatched %q, want %q", got, want)
+	}
+}
+
+func TestHyprctlKeyword(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	srv.SetReply("keyword general:gaps_in wide", "invalid value")
+
+	h := NewHyprctl()
+	if err := h.Keyword(context.Background(), "general:gaps_in", "5"); err != nil {
+		t.Errorf("Keyword() error = %v", err)
+	}
+
+	err := h.Keyword(context.Background(), "general:gaps_in", "wide")
+	if err == nil || err.Error() != "keyword general:gaps_in wide: invalid value" {
+		t.Errorf("Keyword() error = %v, want the reply of Hyprland", err)
+	}
+
+	want := []string{"keyword general:gaps_in 5", "keyword general:gaps_in wide"}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestHyprctlBatch(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	srv.SetReply("dispatch exec missing", "No such file or directory")
+
+	h := NewHyprctl()
+	replies, err := h.Batch(context.Background(),
+		"dispatch workspace 2",
+		"dispatch exec missing",
+		"keyword general:gaps_in 5",
+	)
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+
+	// A failing command does not fail the batch, its reply says so
+	want := []string{"ok", "No such file or directory", "ok"}
+	if !reflect.DeepEqual(replies, want) {
+		t.Errorf("Batch() = %q, want %q", replies, want)
+	}
+
+	if got, want := srv.Dispatches(), []string{"workspace 2", "exec missing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatched %q, want %q", got, want)
+	}
+
+	// Nothing is sent for an empty batch
+	if replies, err := h.Batch(context.Background()); replies != nil || err != nil {
+		t.Errorf("empty Batch() = %q, %v, want nothing", replies, err)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("%d requests after an empty batch, want 3", n)
+	}
+}
+
+func TestHyprctlBatchReplyCount(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+
+	// A reply that contains the separator itself can't be split back into
+	// one reply per command
+	srv.SetReply("dispatch workspace 2", "ok\n\n\nok")
+
+	_, err := NewHyprctl().Batch(context.Background(), "dispatch workspace 2", "dispatch workspace 3")
+	if err == nil || err.Error() != "expected 2 replies to batch, got 3" {
+		t.Errorf("Batch() error = %v, want a reply count mismatch", err)
+	}
+}
@@ -2,48 +2,272 @@ package libs
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net"
-	"os"
 	"strings"
-
-	"github.com/gotk3/gotk3/glib"
+	"sync"
+	"time"
 )
 
-func ListenForHyprlandEvents(updateFunc func(eventType, data string)) {
-	path := os.Getenv("XDG_RUNTIME_DIR")
+// Event is a line of Hyprland's event socket, e.g. "workspace>>2" has the
+// name "workspace" and the data "2".
+type Event struct {
+	Name string
+	Data string
+}
+
+// Subscriber describes what a subscriber of an EventBus wants to hear about.
+type Subscriber struct {
+	// Events are the names of the events passed to Handle. An empty list
+	// subscribes to every event.
+	Events []string
+	// Handle is called on the main thread for every matching event.
+	Handle func(ev Event)
+	// Report, if set, is called on the main thread when the connection to
+	// Hyprland fails, and with nil once it is established again.
+	Report func(err error)
+	// Resync, if set, is called on the main thread after the bus
+	// reconnected, possibly to a new Hyprland instance. Events sent while
+	// the bus was disconnected are lost, so state built from events has to
+	// be queried again.
+	Resync func()
+}
+
+// EventBus shares a single connection to Hyprland's event socket between
+// all its subscribers. The connection is opened with the first
+// subscription, reopened with backoff when it fails, following Hyprland
+// across restarts, and closed when the context of the bus is done.
+// Callbacks are delivered through dispatch, which must call its argument on
+// the GTK main thread, in the order the events arrived.
+type EventBus struct {
+	ctx      context.Context
+	dispatch func(func())
 
-	socketPath := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
-	if socketPath == "" {
-		log.Fatal("HYPRLAND_INSTANCE_SIGNATURE environment variable not set")
+	mu            sync.Mutex
+	subs          map[*Subscription]bool
+	started       bool
+	connected     bool
+	everConnected bool
+	lastFailure   error // set while not connected after a failure
+}
+
+func NewEventBus(ctx context.Context, dispatch func(func())) *EventBus {
+	return &EventBus{
+		ctx:      ctx,
+		dispatch: dispatch,
+		subs:     make(map[*Subscription]bool),
 	}
+}
 
-	socketPath = fmt.Sprintf("%s/hypr/%s/.socket2.sock", path, socketPath)
+// Subscription is a subscriber registered on an EventBus.
+type Subscription struct {
+	bus     *EventBus
+	sub     Subscriber
+	removed bool // only accessed on the main thread
+}
 
-	fmt.Println("Connecting to Hyprland socket:", socketPath)
+// Subscribe registers sub. It must be called on the main thread.
+func (b *EventBus) Subscribe(sub Subscriber) *Subscription {
+	s := &Subscription{bus: b, sub: sub}
 
-	conn, err := net.Dial("unix", socketPath)
-	if err != nil {
-		log.Fatal("Failed to connect to Hyprland socket:", err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[s] = true
+
+	if !b.started {
+		b.started = true
+		go b.run()
+	}
+
+	// Subscribers joining while Hyprland is unreachable learn about it
+	// right away instead of on the next attempt
+	if b.lastFailure != nil && sub.Report != nil {
+		err := b.lastFailure
+		b.dispatch(func() {
+			if !s.removed {
+				sub.Report(err)
+			}
+		})
+	}
+
+	return s
+}
+
+// Unsubscribe stops the delivery of events to the subscription. It must be
+// called on the main thread; no callback runs after it returns.
+func (s *Subscription) Unsubscribe() {
+	s.removed = true
+
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+}
+
+// subscriptions returns the current subscriptions.
+func (b *EventBus) subscriptions() []*Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := make([]*Subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
 	}
 
-	go func() {
-		scanner := bufio.NewScanner(conn)
-		for scanner.Scan() {
-			line := scanner.Text()
-			parts := strings.SplitN(line, ">>", 2)
-			if len(parts) != 2 {
-				continue
+	return subs
+}
+
+// publish hands an event to the subscribers interested in it.
+func (b *EventBus) publish(ev Event) {
+	b.dispatch(func() {
+		for _, s := range b.subscriptions() {
+			if !s.removed && s.wants(ev.Name) {
+				s.sub.Handle(ev)
 			}
+		}
+	})
+}
 
-			eventType := parts[0]
-			data := parts[1]
+// report hands a change of the connection state to the subscribers.
+func (b *EventBus) report(err error) {
+	b.mu.Lock()
+	changed := b.lastFailure != nil || err != nil
+	b.connected = err == nil
+	b.lastFailure = err
+	b.mu.Unlock()
+
+	if !changed {
+		return
+	}
 
-			glib.IdleAdd(func() {
-				updateFunc(eventType, data)
-			})
+	b.dispatch(func() {
+		for _, s := range b.subscriptions() {
+			if !s.removed && s.sub.Report != nil {
+				s.sub.Report(err)
+			}
 		}
+	})
+}
+
+func (s *Subscription) wants(name string) bool {
+	if len(s.sub.Events) == 0 {
+		return true
+	}
+
+	for _, event := range s.sub.Events {
+		if event == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run keeps the bus connected until its context is done.
+func (b *EventBus) run() {
+	backoff := Backoff{Min: time.Second, Max: time.Minute}
+
+	for {
+		connected, err := b.listen()
+		if b.ctx.Err() != nil {
+			return
+		}
+
+		// Connections that worked for a while are retried right away, as
+		// Hyprland was most likely restarted
+		if connected {
+			backoff.Reset()
+		}
+
+		Log.Println("unable to listen for Hyprland events:", err)
+		b.report(fmt.Errorf("unable to listen for Hyprland events: %w (retrying)", err))
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
+
+// listen connects to the event socket of the running Hyprland instance and
+// publishes every event. It blocks until the context of the bus is done or
+// the connection fails, and reports whether it got connected.
+func (b *EventBus) listen() (bool, error) {
+	signature, err := findHyprlandInstance(b.ctx)
+	if err != nil {
+		return false, err
+	}
+
+	socketPath := hyprlandSocketPath(signature, ".socket2.sock")
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(b.ctx, "unix", socketPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to Hyprland socket: %w", err)
+	}
+	defer conn.Close()
+
+	Log.Println("connected to Hyprland socket:", socketPath)
+	b.connect()
+
+	// Unblock the reader when the bus is done
+	stop := context.AfterFunc(b.ctx, func() {
 		conn.Close()
-	}()
+	})
+	defer stop()
+
+	// Unlike bufio.Scanner, ReadString has no limit on the length of a line,
+	// so long window titles cannot end the connection
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("connection closed by Hyprland")
+			}
+			return true, err
+		}
+
+		name, data, ok := strings.Cut(strings.TrimSuffix(line, "\n"), ">>")
+		if !ok {
+			continue
+		}
+
+		b.publish(Event{Name: name, Data: data})
+	}
+}
+
+// Connected reports whether the bus is currently connected to Hyprland.
+func (b *EventBus) Connected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.connected
+}
+
+// connect records a new connection. Subscribers are told to resync after a
+// reconnection, as they may have missed events in between.
+func (b *EventBus) connect() {
+	b.mu.Lock()
+	resync := b.everConnected
+	b.everConnected = true
+	b.mu.Unlock()
+
+	b.report(nil)
+
+	if !resync {
+		return
+	}
+
+	b.dispatch(func() {
+		for _, s := range b.subscriptions() {
+			if !s.removed && s.sub.Resync != nil {
+				s.sub.Resync()
+			}
+		}
+	})
 }
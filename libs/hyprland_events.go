@@ -0,0 +1,470 @@
+package libs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The typed Hyprland events returned by Event.Decode. Field order follows
+// the payload documented by Hyprland. Window addresses are normalized to
+// the "0x" prefixed form used by hyprctl, so they can be compared with the
+// addresses hyprctl reports.
+
+// WorkspaceEvent is "workspace>>NAME".
+type WorkspaceEvent struct{ Name string }
+
+// WorkspaceV2Event is "workspacev2>>ID,NAME".
+type WorkspaceV2Event struct {
+	ID   int
+	Name string
+}
+
+// FocusedMonEvent is "focusedmon>>MONITOR,WORKSPACE".
+type FocusedMonEvent struct {
+	Monitor   string
+	Workspace string
+}
+
+// FocusedMonV2Event is "focusedmonv2>>MONITOR,WORKSPACEID".
+type FocusedMonV2Event struct {
+	Monitor     string
+	WorkspaceID int
+}
+
+// ActiveWindowEvent is "activewindow>>CLASS,TITLE". Both are empty when no
+// window has focus.
+type ActiveWindowEvent struct {
+	Class string
+	Title string
+}
+
+// ActiveWindowV2Event is "activewindowv2>>ADDRESS". Address is empty when
+// no window has focus.
+type ActiveWindowV2Event struct{ Address string }
+
+// FullscreenEvent is "fullscreen>>0|1".
+type FullscreenEvent struct{ Fullscreen bool }
+
+// MonitorAddedEvent is "monitoradded>>NAME".
+type MonitorAddedEvent struct{ Name string }
+
+// MonitorAddedV2Event is "monitoraddedv2>>ID,NAME,DESCRIPTION".
+type MonitorAddedV2Event struct {
+	ID          int
+	Name        string
+	Description string
+}
+
+// MonitorRemovedEvent is "monitorremoved>>NAME".
+type MonitorRemovedEvent struct{ Name string }
+
+// MonitorRemovedV2Event is "monitorremovedv2>>ID,NAME,DESCRIPTION".
+type MonitorRemovedV2Event struct {
+	ID          int
+	Name        string
+	Description string
+}
+
+// CreateWorkspaceEvent is "createworkspace>>NAME".
+type CreateWorkspaceEvent struct{ Name string }
+
+// CreateWorkspaceV2Event is "createworkspacev2>>ID,NAME".
+type CreateWorkspaceV2Event struct {
+	ID   int
+	Name string
+}
+
+// DestroyWorkspaceEvent is "destroyworkspace>>NAME".
+type DestroyWorkspaceEvent struct{ Name string }
+
+// DestroyWorkspaceV2Event is "destroyworkspacev2>>ID,NAME".
+type DestroyWorkspaceV2Event struct {
+	ID   int
+	Name string
+}
+
+// MoveWorkspaceEvent is "moveworkspace>>NAME,MONITOR".
+type MoveWorkspaceEvent struct {
+	Name    string
+	Monitor string
+}
+
+// MoveWorkspaceV2Event is "moveworkspacev2>>ID,NAME,MONITOR".
+type MoveWorkspaceV2Event struct {
+	ID      int
+	Name    string
+	Monitor string
+}
+
+// RenameWorkspaceEvent is "renameworkspace>>ID,NAME".
+type RenameWorkspaceEvent struct {
+	ID   int
+	Name string
+}
+
+// ActiveSpecialEvent is "activespecial>>NAME,MONITOR". Name is empty when
+// the special workspace of the monitor was closed.
+type ActiveSpecialEvent struct {
+	Name    string
+	Monitor string
+}
+
+// ActiveSpecialV2Event is "activespecialv2>>ID,NAME,MONITOR".
+type ActiveSpecialV2Event struct {
+	ID      int
+	Name    string
+	Monitor string
+}
+
+// ActiveLayoutEvent is "activelayout>>KEYBOARD,LAYOUT".
+type ActiveLayoutEvent struct {
+	Keyboard string
+	Layout   string
+}
+
+// OpenWindowEvent is "openwindow>>ADDRESS,WORKSPACE,CLASS,TITLE".
+type OpenWindowEvent struct {
+	Address   string
+	Workspace string
+	Class     string
+	Title     string
+}
+
+// CloseWindowEvent is "closewindow>>ADDRESS".
+type CloseWindowEvent struct{ Address string }
+
+// MoveWindowEvent is "movewindow>>ADDRESS,WORKSPACE".
+type MoveWindowEvent struct {
+	Address   string
+	Workspace string
+}
+
+// MoveWindowV2Event is "movewindowv2>>ADDRESS,WORKSPACEID,WORKSPACE".
+type MoveWindowV2Event struct {
+	Address     string
+	WorkspaceID int
+	Workspace   string
+}
+
+// OpenLayerEvent is "openlayer>>NAMESPACE".
+type OpenLayerEvent struct{ Namespace string }
+
+// CloseLayerEvent is "closelayer>>NAMESPACE".
+type CloseLayerEvent struct{ Namespace string }
+
+// SubmapEvent is "submap>>NAME". Name is empty for the default submap.
+type SubmapEvent struct{ Name string }
+
+// ChangeFloatingModeEvent is "changefloatingmode>>ADDRESS,0|1".
+type ChangeFloatingModeEvent struct {
+	Address  string
+	Floating bool
+}
+
+// UrgentEvent is "urgent>>ADDRESS".
+type UrgentEvent struct{ Address string }
+
+// ScreencastEvent is "screencast>>0|1,OWNER", where Owner is 0 for a
+// monitor share and 1 for a window share.
+type ScreencastEvent struct {
+	Active bool
+	Owner  int
+}
+
+// WindowTitleEvent is "windowtitle>>ADDRESS".
+type WindowTitleEvent struct{ Address string }
+
+// WindowTitleV2Event is "windowtitlev2>>ADDRESS,TITLE".
+type WindowTitleV2Event struct {
+	Address string
+	Title   string
+}
+
+// ToggleGroupEvent is "togglegroup>>0|1,ADDRESS[,ADDRESS...]".
+type ToggleGroupEvent struct {
+	Grouped   bool
+	Addresses []string
+}
+
+// MoveIntoGroupEvent is "moveintogroup>>ADDRESS".
+type MoveIntoGroupEvent struct{ Address string }
+
+// MoveOutOfGroupEvent is "moveoutofgroup>>ADDRESS".
+type MoveOutOfGroupEvent struct{ Address string }
+
+// IgnoreGroupLockEvent is "ignoregrouplock>>0|1".
+type IgnoreGroupLockEvent struct{ Ignored bool }
+
+// LockGroupsEvent is "lockgroups>>0|1".
+type LockGroupsEvent struct{ Locked bool }
+
+// ConfigReloadedEvent is "configreloaded>>".
+type ConfigReloadedEvent struct{}
+
+// PinEvent is "pin>>ADDRESS,0|1".
+type PinEvent struct {
+	Address string
+	Pinned  bool
+}
+
+// MinimizedEvent is "minimized>>ADDRESS,0|1".
+type MinimizedEvent struct {
+	Address   string
+	Minimized bool
+}
+
+// eventDecoders decode the payload of the events they are keyed by.
+var eventDecoders = map[string]func(p *payload) any{
+	"workspace": func(p *payload) any {
+		return WorkspaceEvent{Name: p.rest()}
+	},
+	"workspacev2": func(p *payload) any {
+		return WorkspaceV2Event{ID: p.int(), Name: p.rest()}
+	},
+	"focusedmon": func(p *payload) any {
+		return FocusedMonEvent{Monitor: p.field(), Workspace: p.rest()}
+	},
+	"focusedmonv2": func(p *payload) any {
+		return FocusedMonV2Event{Monitor: p.field(), WorkspaceID: p.int()}
+	},
+	"activewindow": func(p *payload) any {
+		// Classes cannot contain commas but titles can
+		return ActiveWindowEvent{Class: p.field(), Title: p.rest()}
+	},
+	"activewindowv2": func(p *payload) any {
+		return ActiveWindowV2Event{Address: p.address()}
+	},
+	"fullscreen": func(p *payload) any {
+		return FullscreenEvent{Fullscreen: p.bool()}
+	},
+	"monitoradded": func(p *payload) any {
+		return MonitorAddedEvent{Name: p.rest()}
+	},
+	"monitoraddedv2": func(p *payload) any {
+		return MonitorAddedV2Event{ID: p.int(), Name: p.field(), Description: p.rest()}
+	},
+	"monitorremoved": func(p *payload) any {
+		return MonitorRemovedEvent{Name: p.rest()}
+	},
+	"monitorremovedv2": func(p *payload) any {
+		return MonitorRemovedV2Event{ID: p.int(), Name: p.field(), Description: p.rest()}
+	},
+	"createworkspace": func(p *payload) any {
+		return CreateWorkspaceEvent{Name: p.rest()}
+	},
+	"createworkspacev2": func(p *payload) any {
+		return CreateWorkspaceV2Event{ID: p.int(), Name: p.rest()}
+	},
+	"destroyworkspace": func(p *payload) any {
+		return DestroyWorkspaceEvent{Name: p.rest()}
+	},
+	"destroyworkspacev2": func(p *payload) any {
+		return DestroyWorkspaceV2Event{ID: p.int(), Name: p.rest()}
+	},
+	"moveworkspace": func(p *payload) any {
+		// Workspace names can contain commas but monitor names cannot
+		monitor := p.last()
+		return MoveWorkspaceEvent{Name: p.rest(), Monitor: monitor}
+	},
+	"moveworkspacev2": func(p *payload) any {
+		id := p.int()
+		monitor := p.last()
+		return MoveWorkspaceV2Event{ID: id, Name: p.rest(), Monitor: monitor}
+	},
+	"renameworkspace": func(p *payload) any {
+		return RenameWorkspaceEvent{ID: p.int(), Name: p.rest()}
+	},
+	"activespecial": func(p *payload) any {
+		monitor := p.last()
+		return ActiveSpecialEvent{Name: p.rest(), Monitor: monitor}
+	},
+	"activespecialv2": func(p *payload) any {
+		id := p.int()
+		monitor := p.last()
+		return ActiveSpecialV2Event{ID: id, Name: p.rest(), Monitor: monitor}
+	},
+	"activelayout": func(p *payload) any {
+		return ActiveLayoutEvent{Keyboard: p.field(), Layout: p.rest()}
+	},
+	"openwindow": func(p *payload) any {
+		// Titles can contain commas, workspace names and classes are
+		// assumed not to
+		return OpenWindowEvent{Address: p.address(), Workspace: p.field(), Class: p.field(), Title: p.rest()}
+	},
+	"closewindow": func(p *payload) any {
+		return CloseWindowEvent{Address: p.address()}
+	},
+	"movewindow": func(p *payload) any {
+		return MoveWindowEvent{Address: p.address(), Workspace: p.rest()}
+	},
+	"movewindowv2": func(p *payload) any {
+		return MoveWindowV2Event{Address: p.address(), WorkspaceID: p.int(), Workspace: p.rest()}
+	},
+	"openlayer": func(p *payload) any {
+		return OpenLayerEvent{Namespace: p.rest()}
+	},
+	"closelayer": func(p *payload) any {
+		return CloseLayerEvent{Namespace: p.rest()}
+	},
+	"submap": func(p *payload) any {
+		return SubmapEvent{Name: p.rest()}
+	},
+	"changefloatingmode": func(p *payload) any {
+		return ChangeFloatingModeEvent{Address: p.address(), Floating: p.bool()}
+	},
+	"urgent": func(p *payload) any {
+		return UrgentEvent{Address: p.address()}
+	},
+	"screencast": func(p *payload) any {
+		return ScreencastEvent{Active: p.bool(), Owner: p.int()}
+	},
+	"windowtitle": func(p *payload) any {
+		return WindowTitleEvent{Address: p.address()}
+	},
+	"windowtitlev2": func(p *payload) any {
+		return WindowTitleV2Event{Address: p.address(), Title: p.rest()}
+	},
+	"togglegroup": func(p *payload) any {
+		ev := ToggleGroupEvent{Grouped: p.bool()}
+		for !p.done() {
+			ev.Addresses = append(ev.Addresses, p.address())
+		}
+		return ev
+	},
+	"moveintogroup": func(p *payload) any {
+		return MoveIntoGroupEvent{Address: p.address()}
+	},
+	"moveoutofgroup": func(p *payload) any {
+		return MoveOutOfGroupEvent{Address: p.address()}
+	},
+	"ignoregrouplock": func(p *payload) any {
+		return IgnoreGroupLockEvent{Ignored: p.bool()}
+	},
+	"lockgroups": func(p *payload) any {
+		return LockGroupsEvent{Locked: p.bool()}
+	},
+	"configreloaded": func(p *payload) any {
+		return ConfigReloadedEvent{}
+	},
+	"pin": func(p *payload) any {
+		return PinEvent{Address: p.address(), Pinned: p.bool()}
+	},
+	"minimized": func(p *payload) any {
+		return MinimizedEvent{Address: p.address(), Minimized: p.bool()}
+	},
+}
+
+// Decode returns the typed form of the event, e.g. an ActiveWindowEvent for
+// "activewindow". Events without a typed form are returned as they are.
+func (ev Event) Decode() (any, error) {
+	decode, ok := eventDecoders[ev.Name]
+	if !ok {
+		return ev, nil
+	}
+
+	p := &payload{data: ev.Data}
+	decoded := decode(p)
+	if p.err != nil {
+		return nil, fmt.Errorf("invalid %s event %q: %w", ev.Name, ev.Data, p.err)
+	}
+
+	return decoded, nil
+}
+
+// NormalizeAddress returns a window address in the "0x" prefixed form used
+// by hyprctl. Events write addresses without the prefix.
+func NormalizeAddress(address string) string {
+	if address == "" || strings.HasPrefix(address, "0x") {
+		return address
+	}
+
+	return "0x" + address
+}
+
+// payload consumes the comma separated fields of an event. Only the last
+// field of an event can contain commas, so fields are taken from the front
+// and the remainder is returned whole by rest. The first error is kept in
+// err.
+type payload struct {
+	data string
+	used bool // all of data was consumed
+	err  error
+}
+
+func (p *payload) done() bool {
+	return p.used
+}
+
+// field returns the next comma separated field.
+func (p *payload) field() string {
+	if p.used {
+		p.fail(errors.New("missing field"))
+		return ""
+	}
+
+	field, rest, ok := strings.Cut(p.data, ",")
+	p.data = rest
+	p.used = !ok
+
+	return field
+}
+
+// rest returns everything that was not consumed yet.
+func (p *payload) rest() string {
+	if p.used {
+		p.fail(errors.New("missing field"))
+		return ""
+	}
+
+	p.used = true
+	return p.data
+}
+
+// last takes the field after the last comma, for events whose middle field
+// can contain commas.
+func (p *payload) last() string {
+	i := strings.LastIndexByte(p.data, ',')
+	if p.used || i < 0 {
+		p.fail(errors.New("missing field"))
+		return ""
+	}
+
+	last := p.data[i+1:]
+	p.data = p.data[:i]
+
+	return last
+}
+
+func (p *payload) int() int {
+	field := p.field()
+	n, err := strconv.Atoi(field)
+	if err != nil {
+		p.fail(fmt.Errorf("expected a number, got %q", field))
+	}
+
+	return n
+}
+
+func (p *payload) bool() bool {
+	switch field := p.field(); field {
+	case "0":
+		return false
+	case "1":
+		return true
+	default:
+		p.fail(fmt.Errorf("expected 0 or 1, got %q", field))
+		return false
+	}
+}
+
+func (p *payload) address() string {
+	return NormalizeAddress(p.field())
+}
+
+func (p *payload) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
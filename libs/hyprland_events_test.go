@@ -0,0 +1,178 @@
+package libs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// eventFixtures are event lines as Hyprland writes them to its event
+// socket, with their decoded form.
+var eventFixtures = []struct {
+	line string
+	want any
+}{
+	{"workspace>>2", WorkspaceEvent{Name: "2"}},
+	{"workspace>>web, mail", WorkspaceEvent{Name: "web, mail"}},
+	{"workspacev2>>2,2", WorkspaceV2Event{ID: 2, Name: "2"}},
+	{"workspacev2>>-98,special:scratch,pad", WorkspaceV2Event{ID: -98, Name: "special:scratch,pad"}},
+	{"focusedmon>>DP-1,3", FocusedMonEvent{Monitor: "DP-1", Workspace: "3"}},
+	{"focusedmon>>HDMI-A-1,web,mail", FocusedMonEvent{Monitor: "HDMI-A-1", Workspace: "web,mail"}},
+	{"focusedmonv2>>DP-1,3", FocusedMonV2Event{Monitor: "DP-1", WorkspaceID: 3}},
+	{"activewindow>>kitty,~/src: vim main.go", ActiveWindowEvent{Class: "kitty", Title: "~/src: vim main.go"}},
+	{"activewindow>>firefox,Hello, World — Mozilla Firefox", ActiveWindowEvent{Class: "firefox", Title: "Hello, World — Mozilla Firefox"}},
+	{"activewindow>>,", ActiveWindowEvent{}},
+	{"activewindowv2>>5619e1e4c0a0", ActiveWindowV2Event{Address: "0x5619e1e4c0a0"}},
+	{"activewindowv2>>", ActiveWindowV2Event{}},
+	{"fullscreen>>1", FullscreenEvent{Fullscreen: true}},
+	{"fullscreen>>0", FullscreenEvent{Fullscreen: false}},
+	{"monitoradded>>HDMI-A-1", MonitorAddedEvent{Name: "HDMI-A-1"}},
+	{"monitoraddedv2>>1,HDMI-A-1,Dell Inc. DELL U2720Q, rev 2", MonitorAddedV2Event{ID: 1, Name: "HDMI-A-1", Description: "Dell Inc. DELL U2720Q, rev 2"}},
+	{"monitorremoved>>HDMI-A-1", MonitorRemovedEvent{Name: "HDMI-A-1"}},
+	{"monitorremovedv2>>1,HDMI-A-1,Dell Inc. DELL U2720Q", MonitorRemovedV2Event{ID: 1, Name: "HDMI-A-1", Description: "Dell Inc. DELL U2720Q"}},
+	{"createworkspace>>4", CreateWorkspaceEvent{Name: "4"}},
+	{"createworkspacev2>>4,4", CreateWorkspaceV2Event{ID: 4, Name: "4"}},
+	{"createworkspacev2>>-1337,chat, irc", CreateWorkspaceV2Event{ID: -1337, Name: "chat, irc"}},
+	{"destroyworkspace>>4", DestroyWorkspaceEvent{Name: "4"}},
+	{"destroyworkspacev2>>-1337,chat, irc", DestroyWorkspaceV2Event{ID: -1337, Name: "chat, irc"}},
+	{"moveworkspace>>4,DP-1", MoveWorkspaceEvent{Name: "4", Monitor: "DP-1"}},
+	{"moveworkspace>>chat, irc,DP-1", MoveWorkspaceEvent{Name: "chat, irc", Monitor: "DP-1"}},
+	{"moveworkspacev2>>-1337,chat, irc,DP-1", MoveWorkspaceV2Event{ID: -1337, Name: "chat, irc", Monitor: "DP-1"}},
+	{"renameworkspace>>3,code, docs", RenameWorkspaceEvent{ID: 3, Name: "code, docs"}},
+	{"activespecial>>special:scratch,DP-1", ActiveSpecialEvent{Name: "special:scratch", Monitor: "DP-1"}},
+	{"activespecial>>,DP-1", ActiveSpecialEvent{Monitor: "DP-1"}},
+	{"activespecialv2>>-98,special:a,b,DP-1", ActiveSpecialV2Event{ID: -98, Name: "special:a,b", Monitor: "DP-1"}},
+	{"activelayout>>at-translated-set-2-keyboard,English (US)", ActiveLayoutEvent{Keyboard: "at-translated-set-2-keyboard", Layout: "English (US)"}},
+	{"activelayout>>kbd,German (no dead keys, Mac)", ActiveLayoutEvent{Keyboard: "kbd", Layout: "German (no dead keys, Mac)"}},
+	{"openwindow>>5619e1e4c0a0,2,kitty,fish, /home/user", OpenWindowEvent{Address: "0x5619e1e4c0a0", Workspace: "2", Class: "kitty", Title: "fish, /home/user"}},
+	{"closewindow>>5619e1e4c0a0", CloseWindowEvent{Address: "0x5619e1e4c0a0"}},
+	{"movewindow>>5619e1e4c0a0,chat, irc", MoveWindowEvent{Address: "0x5619e1e4c0a0", Workspace: "chat, irc"}},
+	{"movewindowv2>>5619e1e4c0a0,-1337,chat, irc", MoveWindowV2Event{Address: "0x5619e1e4c0a0", WorkspaceID: -1337, Workspace: "chat, irc"}},
+	{"openlayer>>waybar", OpenLayerEvent{Namespace: "waybar"}},
+	{"closelayer>>waybar", CloseLayerEvent{Namespace: "waybar"}},
+	{"submap>>resize", SubmapEvent{Name: "resize"}},
+	{"submap>>", SubmapEvent{}},
+	{"changefloatingmode>>5619e1e4c0a0,1", ChangeFloatingModeEvent{Address: "0x5619e1e4c0a0", Floating: true}},
+	{"urgent>>5619e1e4c0a0", UrgentEvent{Address: "0x5619e1e4c0a0"}},
+	{"screencast>>1,0", ScreencastEvent{Active: true, Owner: 0}},
+	{"screencast>>0,1", ScreencastEvent{Active: false, Owner: 1}},
+	{"windowtitle>>5619e1e4c0a0", WindowTitleEvent{Address: "0x5619e1e4c0a0"}},
+	{"windowtitlev2>>5619e1e4c0a0,Hello, World", WindowTitleV2Event{Address: "0x5619e1e4c0a0", Title: "Hello, World"}},
+	{"windowtitlev2>>5619e1e4c0a0,", WindowTitleV2Event{Address: "0x5619e1e4c0a0"}},
+	{"togglegroup>>1,5619e1e4c0a0,5619e1e4c1b0", ToggleGroupEvent{Grouped: true, Addresses: []string{"0x5619e1e4c0a0", "0x5619e1e4c1b0"}}},
+	{"togglegroup>>0,5619e1e4c0a0", ToggleGroupEvent{Grouped: false, Addresses: []string{"0x5619e1e4c0a0"}}},
+	{"moveintogroup>>5619e1e4c0a0", MoveIntoGroupEvent{Address: "0x5619e1e4c0a0"}},
+	{"moveoutofgroup>>5619e1e4c0a0", MoveOutOfGroupEvent{Address: "0x5619e1e4c0a0"}},
+	{"ignoregrouplock>>1", IgnoreGroupLockEvent{Ignored: true}},
+	{"lockgroups>>0", LockGroupsEvent{Locked: false}},
+	{"configreloaded>>", ConfigReloadedEvent{}},
+	{"pin>>5619e1e4c0a0,1", PinEvent{Address: "0x5619e1e4c0a0", Pinned: true}},
+	{"minimized>>5619e1e4c0a0,0", MinimizedEvent{Address: "0x5619e1e4c0a0", Minimized: false}},
+
+	// Events without a typed form are passed through
+	{"bell>>5619e1e4c0a0", Event{Name: "bell", Data: "5619e1e4c0a0"}},
+}
+
+// parseEventLine splits a fixture line like the event reader does.
+func parseEventLine(t *testing.T, line string) Event {
+	t.Helper()
+
+	name, data, ok := strings.Cut(line, ">>")
+	if !ok {
+		t.Fatalf("invalid fixture %q", line)
+	}
+
+	return Event{Name: name, Data: data}
+}
+
+func TestEventDecode(t *testing.T) {
+	for _, tt := range eventFixtures {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseEventLine(t, tt.line).Decode()
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEventDecodeCoverage keeps the fixtures in sync with the decoders.
+func TestEventDecodeCoverage(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tt := range eventFixtures {
+		covered[parseEventLine(t, tt.line).Name] = true
+	}
+
+	for name := range eventDecoders {
+		if !covered[name] {
+			t.Errorf("no fixture for %s events", name)
+		}
+	}
+}
+
+func TestEventDecodeMalformed(t *testing.T) {
+	lines := []string{
+		"workspacev2>>two,2",
+		"workspacev2>>2",
+		"focusedmonv2>>DP-1",
+		"focusedmonv2>>DP-1,three",
+		"fullscreen>>2",
+		"fullscreen>>",
+		"monitoraddedv2>>HDMI-A-1",
+		"createworkspacev2>>4",
+		"destroyworkspacev2>>,4",
+		"moveworkspace>>4",
+		"moveworkspacev2>>4,DP-1",
+		"renameworkspace>>three,code",
+		"activespecial>>special:scratch",
+		"activespecialv2>>-98,DP-1",
+		"openwindow>>5619e1e4c0a0,2,kitty",
+		"movewindowv2>>5619e1e4c0a0,2",
+		"movewindowv2>>5619e1e4c0a0,web,web",
+		"changefloatingmode>>5619e1e4c0a0",
+		"changefloatingmode>>5619e1e4c0a0,yes",
+		"screencast>>1",
+		"screencast>>1,window",
+		"togglegroup>>maybe,5619e1e4c0a0",
+		"ignoregrouplock>>",
+		"lockgroups>>true",
+		"pin>>5619e1e4c0a0",
+		"minimized>>5619e1e4c0a0,2",
+	}
+
+	for _, line := range lines {
+		t.Run(line, func(t *testing.T) {
+			ev := parseEventLine(t, line)
+
+			got, err := ev.Decode()
+			if err == nil {
+				t.Fatalf("Decode() = %#v, want an error", got)
+			}
+
+			if !strings.Contains(err.Error(), ev.Name) {
+				t.Errorf("Decode() error %q does not name the event", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"5619e1e4c0a0", "0x5619e1e4c0a0"},
+		{"0x5619e1e4c0a0", "0x5619e1e4c0a0"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAddress(tt.address); got != tt.want {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package libs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hyprlandInstance caches the signature of the running Hyprland instance.
+// It starts out as $HYPRLAND_INSTANCE_SIGNATURE and is replaced when the
+// event bus finds that Hyprland was restarted under a new signature. The
+// cache is dropped when the environment it was found in changes.
+var hyprlandInstance struct {
+	mu        sync.Mutex
+	env       string
+	signature string
+}
+
+// hyprlandEnv identifies the environment the cached signature was found in.
+func hyprlandEnv() string {
+	return os.Getenv("XDG_RUNTIME_DIR") + "\x00" + os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+}
+
+// cachedHyprlandInstance returns the cached signature, or "" if there is
+// none for the current environment.
+func cachedHyprlandInstance() string {
+	hyprlandInstance.mu.Lock()
+	defer hyprlandInstance.mu.Unlock()
+
+	if hyprlandInstance.env != hyprlandEnv() {
+		return ""
+	}
+
+	return hyprlandInstance.signature
+}
+
+// hyprlandRuntimeDir returns the directory holding the socket directories
+// of all Hyprland instances.
+func hyprlandRuntimeDir() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errors.New("XDG_RUNTIME_DIR environment variable not set")
+	}
+
+	return filepath.Join(dir, "hypr"), nil
+}
+
+func hyprlandSocketPath(signature, name string) string {
+	dir, _ := hyprlandRuntimeDir()
+	return filepath.Join(dir, signature, name)
+}
+
+// hyprlandSocket returns the path of one of the sockets of the running
+// Hyprland instance.
+func hyprlandSocket(name string) (string, error) {
+	signature := cachedHyprlandInstance()
+	if signature == "" {
+		var err error
+		signature, err = findHyprlandInstance(context.Background())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hyprlandSocketPath(signature, name), nil
+}
+
+// findHyprlandInstance returns the signature of a running Hyprland
+// instance: the cached one or $HYPRLAND_INSTANCE_SIGNATURE if it still
+// answers, otherwise the most recently started instance that does. The
+// result is cached for hyprlandSocket.
+func findHyprlandInstance(ctx context.Context) (string, error) {
+	dir, err := hyprlandRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+
+	cached := cachedHyprlandInstance()
+	candidates := []string{cached, os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("unable to list Hyprland instances: %w", err)
+	}
+
+	type instance struct {
+		signature string
+		started   time.Time
+	}
+	var instances []instance
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		instances = append(instances, instance{entry.Name(), info.ModTime()})
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].started.After(instances[j].started)
+	})
+	for _, inst := range instances {
+		candidates = append(candidates, inst.signature)
+	}
+
+	for _, signature := range candidates {
+		if signature == "" || !hyprlandAlive(ctx, signature) {
+			continue
+		}
+
+		if signature != cached {
+			if cached != "" {
+				Log.Println("found new Hyprland instance:", signature)
+			}
+
+			hyprlandInstance.mu.Lock()
+			hyprlandInstance.env = hyprlandEnv()
+			hyprlandInstance.signature = signature
+			hyprlandInstance.mu.Unlock()
+		}
+
+		return signature, nil
+	}
+
+	return "", errors.New("no running Hyprland instance found")
+}
+
+// hyprlandAlive reports whether the instance with the given signature
+// accepts connections. Sockets of instances that exited stay behind.
+func hyprlandAlive(ctx context.Context, signature string) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", hyprlandSocketPath(signature, ".socket.sock"))
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+	return true
+}
+
+// HyprlandRunning reports whether a Hyprland instance answers in the
+// current runtime directory.
+func HyprlandRunning(ctx context.Context) bool {
+	_, err := findHyprlandInstance(ctx)
+	return err == nil
+}
@@ -0,0 +1,510 @@
+package libs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// StateSlice selects parts of a HyprlandState.
+type StateSlice uint
+
+const (
+	// StateMonitors covers Monitors, including the workspace each of them
+	// shows.
+	StateMonitors StateSlice = 1 << iota
+	// StateWorkspaces covers Workspaces.
+	StateWorkspaces
+	// StateClients covers Clients.
+	StateClients
+	// StateFocus covers FocusedMonitor, ActiveWorkspace and ActiveWindow.
+	StateFocus
+
+	StateAll = StateMonitors | StateWorkspaces | StateClients | StateFocus
+)
+
+// stateSlices is the number of slices.
+const stateSlices = 4
+
+// defaultReconcileInterval is the time between two full queries made to
+// catch up with changes that events do not report.
+const defaultReconcileInterval = 30 * time.Second
+
+// maxStaleQueries is the number of query results in a row that are
+// discarded because events changed their slices meanwhile. The next results
+// are applied regardless, so that a steady stream of events cannot keep the
+// state from catching up.
+const maxStaleQueries = 3
+
+// stateEvents are the events that change the state.
+var stateEvents = []string{
+	"workspacev2", "focusedmonv2", "activewindowv2", "windowtitlev2",
+	"openwindow", "closewindow", "movewindowv2", "changefloatingmode", "pin",
+	"createworkspacev2", "destroyworkspacev2", "renameworkspace", "moveworkspacev2",
+	"monitoraddedv2", "monitorremovedv2",
+}
+
+// Watcher describes what a watcher of a HyprlandState wants to hear about.
+type Watcher struct {
+	// Slices are the parts of the state passed changes are reported for.
+	Slices StateSlice
+	// Changed is called on the main thread when one of the slices changed.
+	Changed func()
+	// Report, if set, is called on the main thread when the state cannot
+	// be kept up to date, and with nil once it can again.
+	Report func(err error)
+}
+
+// HyprlandState mirrors the monitors, workspaces and windows of Hyprland.
+// Events are applied as they arrive on the event bus, either directly or by
+// querying the slices they affect again, and everything is queried again
+// periodically to catch up with changes events do not report.
+//
+// Mirroring starts with the first watcher. The state is only read and
+// changed on the main thread; queries run in the background and hand their
+// results over through the dispatch function of the event bus.
+type HyprlandState struct {
+	ctx     context.Context
+	bus     *EventBus
+	hyprctl *Hyprctl
+
+	// ReconcileInterval is the time between two full queries.
+	ReconcileInterval time.Duration
+
+	// Accessed on the main thread only
+	monitors     []Monitor
+	workspaces   []Workspace
+	clients      []Client
+	activeWindow string // address of the focused window
+	loaded       bool
+	running      bool
+	watches      map[*StateWatch]bool
+	failure      error
+	staleQueries int    // query results discarded in a row
+	clientLists  uint64 // client lists applied from queries
+
+	mu      sync.Mutex
+	dirty   StateSlice          // slices waiting to be queried
+	changes [stateSlices]uint64 // number of events that changed each slice
+	wake    chan struct{}
+}
+
+func NewHyprlandState(ctx context.Context, bus *EventBus, hyprctl *Hyprctl) *HyprlandState {
+	return &HyprlandState{
+		ctx:               ctx,
+		bus:               bus,
+		hyprctl:           hyprctl,
+		ReconcileInterval: defaultReconcileInterval,
+		watches:           make(map[*StateWatch]bool),
+		wake:              make(chan struct{}, 1),
+	}
+}
+
+// StateWatch is a watcher registered on a HyprlandState.
+type StateWatch struct {
+	state   *HyprlandState
+	watcher Watcher
+	removed bool
+}
+
+// Watch registers w. It must be called on the main thread. If the state is
+// already known, w.Changed is called right away.
+func (s *HyprlandState) Watch(w Watcher) *StateWatch {
+	watch := &StateWatch{state: s, watcher: w}
+
+	if !s.running {
+		s.running = true
+		s.start()
+	}
+	s.watches[watch] = true
+
+	s.bus.dispatch(func() {
+		if watch.removed {
+			return
+		}
+		if s.failure != nil && w.Report != nil {
+			w.Report(s.failure)
+		}
+		if s.loaded {
+			w.Changed()
+		}
+	})
+
+	return watch
+}
+
+// Stop ends the watch. It must be called on the main thread; no callback
+// runs after it returns.
+func (w *StateWatch) Stop() {
+	w.removed = true
+	delete(w.state.watches, w)
+}
+
+// Monitors returns the monitors.
+func (s *HyprlandState) Monitors() []Monitor {
+	return s.monitors
+}
+
+// Workspaces returns the workspaces, special workspaces included.
+func (s *HyprlandState) Workspaces() []Workspace {
+	return s.workspaces
+}
+
+// Clients returns the windows.
+func (s *HyprlandState) Clients() []Client {
+	return s.clients
+}
+
+// ClientLists returns the number of client lists applied from queries so
+// far, whether they changed the clients or not. Callers compare it with an
+// earlier value to tell whether Hyprland was asked for the clients since.
+func (s *HyprlandState) ClientLists() uint64 {
+	return s.clientLists
+}
+
+// Client returns the window with the given address.
+func (s *HyprlandState) Client(address string) (Client, bool) {
+	for _, c := range s.clients {
+		if c.Address == address {
+			return c, true
+		}
+	}
+
+	return Client{}, false
+}
+
+// FocusedMonitor returns the monitor that has focus, or nil if unknown.
+func (s *HyprlandState) FocusedMonitor() *Monitor {
+	for i := range s.monitors {
+		if s.monitors[i].Focused {
+			return &s.monitors[i]
+		}
+	}
+
+	return nil
+}
+
+// ActiveWorkspace returns the workspace shown on the focused monitor.
+func (s *HyprlandState) ActiveWorkspace() WorkspaceRef {
+	if m := s.FocusedMonitor(); m != nil {
+		return m.ActiveWorkspace
+	}
+
+	return WorkspaceRef{}
+}
+
+// ActiveWindow returns the focused window, or nil if no window has focus.
+func (s *HyprlandState) ActiveWindow() *Client {
+	if s.activeWindow == "" {
+		return nil
+	}
+
+	if c, ok := s.Client(s.activeWindow); ok {
+		return &c
+	}
+
+	return nil
+}
+
+// start subscribes to the event bus and launches the background queries.
+func (s *HyprlandState) start() {
+	s.bus.Subscribe(Subscriber{
+		Events: stateEvents,
+		Handle: s.handle,
+		Report: func(err error) {
+			s.report(err)
+
+			// Catch up once Hyprland is reachable again
+			if err == nil {
+				s.requery(StateAll)
+			}
+		},
+		Resync: func() {
+			s.requery(StateAll)
+		},
+	})
+
+	go s.run()
+	s.requery(StateAll)
+}
+
+// run queries the dirty slices whenever there are some, and all of them
+// every ReconcileInterval.
+func (s *HyprlandState) run() {
+	ticker := time.NewTicker(s.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.markDirty(StateAll)
+		case <-s.wake:
+		}
+
+		s.mu.Lock()
+		dirty := s.dirty
+		s.dirty = 0
+		s.mu.Unlock()
+
+		if dirty != 0 {
+			s.query(dirty)
+		}
+	}
+}
+
+func (s *HyprlandState) markDirty(slices StateSlice) {
+	s.mu.Lock()
+	s.dirty |= slices
+	s.mu.Unlock()
+}
+
+// markChanged counts an event that changed slices.
+func (s *HyprlandState) markChanged(slices StateSlice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.changes {
+		if slices&(1<<i) != 0 {
+			s.changes[i]++
+		}
+	}
+}
+
+// changedSince returns the slices events changed since changes was taken.
+func (s *HyprlandState) changedSince(changes [stateSlices]uint64) StateSlice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed StateSlice
+	for i := range s.changes {
+		if s.changes[i] != changes[i] {
+			changed |= 1 << i
+		}
+	}
+
+	return changed
+}
+
+// requery schedules a query of slices.
+func (s *HyprlandState) requery(slices StateSlice) {
+	s.markDirty(slices)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// query fetches slices from Hyprland and applies them on the main thread.
+func (s *HyprlandState) query(slices StateSlice) {
+	s.mu.Lock()
+	changes := s.changes
+	s.mu.Unlock()
+
+	var (
+		monitors     []Monitor
+		workspaces   []Workspace
+		clients      []Client
+		activeWindow *Client
+		err          error
+	)
+
+	// The focused monitor and its workspace are part of the monitors
+	if slices&(StateMonitors|StateFocus) != 0 {
+		monitors, err = s.hyprctl.Monitors(s.ctx)
+	}
+	if err == nil && slices&StateWorkspaces != 0 {
+		workspaces, err = s.hyprctl.Workspaces(s.ctx)
+	}
+	if err == nil && slices&StateClients != 0 {
+		clients, err = s.hyprctl.Clients(s.ctx)
+	}
+	if err == nil && slices&StateFocus != 0 {
+		activeWindow, err = s.hyprctl.ActiveWindow(s.ctx)
+	}
+
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		Log.Println("unable to query Hyprland state:", err)
+		s.bus.dispatch(func() {
+			s.report(fmt.Errorf("unable to query Hyprland state: %w", err))
+		})
+		return
+	}
+
+	s.bus.dispatch(func() {
+		// Events handled while querying may be newer than the results of the
+		// slices they changed, which would undo them. Those slices are
+		// queried again and the others applied.
+		stale := s.changedSince(changes) & slices
+		if stale&(StateMonitors|StateFocus) != 0 {
+			// Both are applied from the monitors
+			stale |= (StateMonitors | StateFocus) & slices
+		}
+
+		if stale != 0 && s.loaded && s.staleQueries < maxStaleQueries {
+			s.staleQueries++
+			s.requery(stale)
+			slices &^= stale
+		} else {
+			s.staleQueries = 0
+		}
+
+		if slices != 0 {
+			s.apply(slices, monitors, workspaces, clients, activeWindow)
+		}
+	})
+}
+
+// apply replaces slices with the results of a query.
+func (s *HyprlandState) apply(slices StateSlice, monitors []Monitor, workspaces []Workspace, clients []Client, activeWindow *Client) {
+	var changed StateSlice
+
+	if slices&(StateMonitors|StateFocus) != 0 && !reflect.DeepEqual(monitors, s.monitors) {
+		changed |= StateMonitors | StateFocus
+		s.monitors = monitors
+	}
+	if slices&StateWorkspaces != 0 && !reflect.DeepEqual(workspaces, s.workspaces) {
+		changed |= StateWorkspaces
+		s.workspaces = workspaces
+	}
+	if slices&StateClients != 0 {
+		s.clientLists++
+		if !reflect.DeepEqual(clients, s.clients) {
+			changed |= StateClients | StateFocus
+			s.clients = clients
+		}
+	}
+	if slices&StateFocus != 0 {
+		address := ""
+		if activeWindow != nil {
+			address = activeWindow.Address
+		}
+		if address != s.activeWindow {
+			changed |= StateFocus
+			s.activeWindow = address
+		}
+	}
+
+	first := !s.loaded
+	s.loaded = true
+
+	s.report(nil)
+
+	if first {
+		changed = StateAll
+	}
+	s.notify(changed)
+}
+
+// handle applies an event, on the main thread.
+func (s *HyprlandState) handle(ev Event) {
+	decoded, err := ev.Decode()
+	if err != nil {
+		Log.Println("unable to decode Hyprland event:", err)
+		return
+	}
+
+	var changed StateSlice
+
+	switch ev := decoded.(type) {
+	case WorkspaceV2Event:
+		if m := s.FocusedMonitor(); m != nil {
+			m.ActiveWorkspace = WorkspaceRef{ID: ev.ID, Name: ev.Name}
+			changed |= StateMonitors | StateFocus
+		}
+		s.markChanged(StateMonitors | StateFocus)
+
+	case FocusedMonV2Event:
+		for i := range s.monitors {
+			m := &s.monitors[i]
+			m.Focused = m.Name == ev.Monitor
+			if m.Focused {
+				m.ActiveWorkspace = WorkspaceRef{ID: ev.WorkspaceID, Name: s.workspaceName(ev.WorkspaceID)}
+			}
+		}
+		changed |= StateMonitors | StateFocus
+		s.markChanged(StateMonitors | StateFocus)
+
+	case ActiveWindowV2Event:
+		s.activeWindow = ev.Address
+		changed |= StateFocus
+		s.markChanged(StateFocus)
+
+	case WindowTitleV2Event:
+		for i := range s.clients {
+			if s.clients[i].Address == ev.Address {
+				s.clients[i].Title = ev.Title
+				changed |= StateClients | StateFocus
+			}
+		}
+		s.markChanged(StateClients)
+
+	case OpenWindowEvent, CloseWindowEvent, MoveWindowV2Event, ChangeFloatingModeEvent, PinEvent:
+		// Window counts of the workspaces change along
+		s.requery(StateClients | StateWorkspaces)
+
+	case CreateWorkspaceV2Event, DestroyWorkspaceV2Event, RenameWorkspaceEvent, MoveWorkspaceV2Event:
+		s.requery(StateWorkspaces | StateMonitors)
+
+	case MonitorAddedV2Event, MonitorRemovedV2Event:
+		s.requery(StateMonitors | StateWorkspaces)
+	}
+
+	s.notify(changed)
+}
+
+// workspaceName returns the name of the workspace with the given ID.
+func (s *HyprlandState) workspaceName(id int) string {
+	for _, ws := range s.workspaces {
+		if ws.ID == id {
+			return ws.Name
+		}
+	}
+
+	return fmt.Sprint(id)
+}
+
+// notify calls the watchers of the changed slices.
+func (s *HyprlandState) notify(changed StateSlice) {
+	if changed == 0 || !s.loaded {
+		return
+	}
+
+	for _, w := range s.watchList() {
+		if !w.removed && w.watcher.Slices&changed != 0 {
+			w.watcher.Changed()
+		}
+	}
+}
+
+// report hands query and connection failures to the watchers.
+func (s *HyprlandState) report(err error) {
+	if err == nil && s.failure == nil {
+		return
+	}
+	s.failure = err
+
+	for _, w := range s.watchList() {
+		if !w.removed && w.watcher.Report != nil {
+			w.watcher.Report(err)
+		}
+	}
+}
+
+// watchList returns the current watches, which callbacks may change.
+func (s *HyprlandState) watchList() []*StateWatch {
+	watches := make([]*StateWatch, 0, len(s.watches))
+	for w := range s.watches {
+		watches = append(watches, w)
+	}
+
+	return watches
+}
@@ -0,0 +1,81 @@
+package libs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grentenrg/go-bar/libs/hyprtest"
+)
+
+// newTestState returns a state following srv whose main thread is run by
+// hand: callbacks queue up until the returned function runs them.
+func newTestState(t *testing.T, srv *hyprtest.Server) (*HyprlandState, func()) {
+	t.Helper()
+
+	var queued []func()
+	dispatch := func(f func()) {
+		queued = append(queued, f)
+	}
+	runQueued := func() {
+		for len(queued) > 0 {
+			f := queued[0]
+			queued = queued[1:]
+			f()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bus := NewEventBus(ctx, dispatch)
+	return NewHyprlandState(ctx, bus, NewHyprctl()), runQueued
+}
+
+func TestHyprlandStateStaleQueries(t *testing.T) {
+	srv := hyprtest.NewServer(t)
+	srv.SetJSON("monitors", []Monitor{{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: WorkspaceRef{ID: 1, Name: "1"}}})
+	srv.SetJSON("workspaces", []Workspace{{ID: 1, Name: "1", Monitor: "DP-1", Windows: 1}})
+	srv.SetJSON("clients", []Client{{Address: "0x1", Title: "old", Workspace: WorkspaceRef{ID: 1, Name: "1"}}})
+	srv.SetJSON("activewindow", Client{Address: "0x1", Title: "old"})
+
+	s, runQueued := newTestState(t, srv)
+
+	s.query(StateAll)
+	runQueued()
+	if !s.loaded {
+		t.Fatal("state not loaded after the first query")
+	}
+
+	// Hyprland reports an old title while title events keep arriving, as
+	// they do for a terminal printing its working directory
+	srv.SetJSON("workspaces", []Workspace{{ID: 1, Name: "1", Monitor: "DP-1", Windows: 2}})
+	for i := 0; i <= maxStaleQueries; i++ {
+		s.query(StateClients | StateWorkspaces)
+		s.handle(Event{Name: "windowtitlev2", Data: "1,new"})
+		s.dirty = 0
+		runQueued()
+
+		if got := s.workspaces[0].Windows; got != 2 {
+			t.Fatalf("query %d: workspaces not applied, got %d windows", i, got)
+		}
+
+		c, _ := s.Client("0x1")
+		stale := i < maxStaleQueries
+		switch {
+		case stale && c.Title != "new":
+			t.Fatalf("query %d: title event undone, got title %q", i, c.Title)
+		case stale && s.dirty != StateClients:
+			t.Fatalf("query %d: requeried %b, want the clients", i, s.dirty)
+		case !stale && c.Title != "old":
+			t.Fatalf("query %d: clients never caught up, got title %q", i, c.Title)
+		}
+	}
+
+	// Results without intervening events are applied right away
+	s.query(StateClients)
+	s.dirty = 0
+	runQueued()
+	if s.dirty != 0 || s.staleQueries != 0 {
+		t.Errorf("query without events: requeried %b after %d stale queries", s.dirty, s.staleQueries)
+	}
+}
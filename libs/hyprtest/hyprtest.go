@@ -0,0 +1,313 @@
+// Package hyprtest provides a fake Hyprland instance for tests of code
+// talking to Hyprland, so that it can run without a compositor.
+//
+// A Server serves both sockets of an instance from a temporary
+// $XDG_RUNTIME_DIR/hypr/<signature>/ directory and points the environment
+// of the test at it:
+//
+//	srv := hyprtest.NewServer(t)
+//	srv.SetJSON("workspaces", []libs.Workspace{{ID: 1, Name: "1"}})
+//	// ... start the code under test, then
+//	srv.WaitListeners(1)
+//	srv.Emit("workspacev2", "1,1")
+//	// ... and check what it dispatched
+//	srv.Dispatches() // e.g. ["workspace 2"]
+package hyprtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchSeparator separates the replies to the commands of a batch.
+const batchSeparator = "\n\n\n"
+
+// Server is a fake Hyprland instance. Its methods may be called
+// concurrently.
+type Server struct {
+	// Signature is the instance signature, as set in
+	// $HYPRLAND_INSTANCE_SIGNATURE.
+	Signature string
+	// Dir is the directory holding the sockets.
+	Dir string
+
+	t        testing.TB
+	requests net.Listener
+	events   net.Listener
+
+	mu         sync.Mutex
+	replies    map[string]string
+	received   []string
+	dispatches []string
+	listeners  []net.Conn
+	changed    chan struct{} // closed and replaced when listeners change
+}
+
+// NewServer starts a fake instance in a temporary runtime directory and
+// sets $XDG_RUNTIME_DIR and $HYPRLAND_INSTANCE_SIGNATURE for the rest of
+// the test. It is stopped when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	// Socket paths are limited to about a hundred bytes, which the
+	// directories of t.TempDir can exceed
+	runtimeDir, err := os.MkdirTemp("", "hyprtest")
+	if err != nil {
+		t.Fatalf("hyprtest: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(runtimeDir)
+	})
+
+	s := &Server{
+		Signature: fmt.Sprintf("hyprtest_%d", time.Now().UnixNano()),
+		t:         t,
+		replies:   make(map[string]string),
+		changed:   make(chan struct{}),
+	}
+	s.Dir = filepath.Join(runtimeDir, "hypr", s.Signature)
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		t.Fatalf("hyprtest: %v", err)
+	}
+
+	s.requests, err = net.Listen("unix", filepath.Join(s.Dir, ".socket.sock"))
+	if err != nil {
+		t.Fatalf("hyprtest: %v", err)
+	}
+
+	s.events, err = net.Listen("unix", filepath.Join(s.Dir, ".socket2.sock"))
+	if err != nil {
+		s.requests.Close()
+		t.Fatalf("hyprtest: %v", err)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", s.Signature)
+
+	go s.serveRequests()
+	go s.serveEvents()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetReply sets the raw reply to a request, e.g. "j/monitors" or
+// "dispatch exec foo". Requests without a reply get "ok" if they are
+// dispatch or keyword commands, and "unknown request" otherwise.
+func (s *Server) SetReply(request, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replies[request] = reply
+}
+
+// SetJSON sets the reply to a JSON query such as "workspaces" or
+// "activewindow" to v encoded as JSON.
+func (s *Server) SetJSON(query string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatalf("hyprtest: unable to encode %s reply: %v", query, err)
+	}
+
+	s.SetReply("j/"+query, string(data))
+}
+
+// Requests returns the requests received so far, in order. The commands of
+// a batch are listed one by one.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.received...)
+}
+
+// Dispatches returns the arguments of the dispatch commands received so
+// far, e.g. "workspace 2".
+func (s *Server) Dispatches() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.dispatches...)
+}
+
+// Emit sends an event to every connected event listener.
+func (s *Server) Emit(name, data string) {
+	s.EmitLines(name + ">>" + data)
+}
+
+// EmitLines sends raw event lines, e.g. "activewindow>>kitty,~", to every
+// connected event listener, in a single write.
+func (s *Server) EmitLines(lines ...string) {
+	payload := strings.Join(lines, "\n") + "\n"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.listeners {
+		if _, err := io.WriteString(conn, payload); err != nil {
+			s.t.Logf("hyprtest: unable to emit events: %v", err)
+		}
+	}
+}
+
+// Listeners returns the number of connected event listeners.
+func (s *Server) Listeners() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.listeners)
+}
+
+// WaitListeners waits until at least n event listeners are connected, and
+// fails the test if that takes more than five seconds.
+func (s *Server) WaitListeners(n int) {
+	s.t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		count, changed := len(s.listeners), s.changed
+		s.mu.Unlock()
+
+		if count >= n {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			s.t.Fatalf("hyprtest: %d event listeners connected, want %d", count, n)
+		}
+	}
+}
+
+// DropListeners closes the connections of the event listeners, as happens
+// when Hyprland exits. Listeners can connect again afterwards.
+func (s *Server) DropListeners() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.listeners {
+		conn.Close()
+	}
+	s.listeners = nil
+	s.notifyLocked()
+}
+
+// Close stops the instance. It is called automatically when the test ends.
+func (s *Server) Close() {
+	s.requests.Close()
+	s.events.Close()
+	s.DropListeners()
+}
+
+func (s *Server) serveRequests() {
+	for {
+		conn, err := s.requests.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.serveRequest(conn)
+	}
+}
+
+// serveRequest answers a single request and closes the connection, like
+// Hyprland does.
+func (s *Server) serveRequest(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Requests are not terminated; a client writes one and waits for the
+	// reply
+	buf := make([]byte, 8192)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+
+	io.WriteString(conn, s.reply(string(buf[:n])))
+}
+
+// reply records a request and returns its reply.
+func (s *Server) reply(request string) string {
+	if batch, ok := strings.CutPrefix(request, "[[BATCH]]"); ok {
+		var replies []string
+		for _, command := range strings.Split(batch, ";") {
+			replies = append(replies, s.reply(strings.TrimSpace(command)))
+		}
+		return strings.Join(replies, batchSeparator)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received = append(s.received, request)
+
+	command, args, _ := strings.Cut(request, " ")
+	if command == "dispatch" {
+		s.dispatches = append(s.dispatches, args)
+	}
+
+	if reply, ok := s.replies[request]; ok {
+		return reply
+	}
+
+	switch command {
+	case "dispatch", "keyword":
+		return "ok"
+	default:
+		return "unknown request"
+	}
+}
+
+func (s *Server) serveEvents() {
+	for {
+		conn, err := s.events.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.listeners = append(s.listeners, conn)
+		s.notifyLocked()
+		s.mu.Unlock()
+
+		go s.watchListener(conn)
+	}
+}
+
+// watchListener forgets a listener once it disconnects.
+func (s *Server) watchListener(conn net.Conn) {
+	// Listeners never write, so reading only returns when the connection
+	// is closed by either side
+	bufio.NewReader(conn).ReadByte()
+	conn.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.listeners {
+		if other == conn {
+			s.listeners = append(s.listeners[:i], s.listeners[i+1:]...)
+			s.notifyLocked()
+			break
+		}
+	}
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
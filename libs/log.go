@@ -0,0 +1,10 @@
+package libs
+
+import (
+	"log"
+	"os"
+)
+
+// Log reports what goes wrong while the bar runs. It writes to standard
+// error so that nothing mixes with the output of commands such as msg.
+var Log = log.New(os.Stderr, "go-bar: ", 0)
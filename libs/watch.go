@@ -0,0 +1,121 @@
+package libs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// watchDebounce is how long WatchFiles waits for further events before
+// reporting a change. Editors usually write a file in several steps.
+const watchDebounce = 100 * time.Millisecond
+
+// WatchFiles calls onChange with the path of every watched file that was
+// written, created, replaced or removed. The parent directories are watched
+// rather than the files themselves so that editors replacing a file through
+// a rename are noticed too. It blocks until ctx is done.
+func WatchFiles(ctx context.Context, paths []string, onChange func(path string)) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("unable to initialize inotify: %w", err)
+	}
+
+	// A non-blocking descriptor wrapped in an *os.File uses the runtime
+	// poller, so closing it unblocks a pending Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	defer file.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		file.Close()
+	})
+	defer stop()
+
+	// Map of watch descriptor to directory, and of directory to the watched
+	// file paths it contains
+	dirs := make(map[int32]string)
+	files := make(map[string]map[string]string)
+
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE
+
+	for _, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("unable to resolve %s: %w", path, err)
+		}
+
+		dir, name := filepath.Split(path)
+		dir = filepath.Clean(dir)
+
+		if _, ok := files[dir]; !ok {
+			wd, err := syscall.InotifyAddWatch(fd, dir, mask)
+			if err != nil {
+				return fmt.Errorf("unable to watch %s: %w", dir, err)
+			}
+
+			dirs[int32(wd)] = dir
+			files[dir] = make(map[string]string)
+		}
+
+		files[dir][name] = path
+	}
+
+	events := make(chan string)
+	readErr := make(chan error, 1)
+
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				name := strings.TrimRight(string(nameBytes), "\x00")
+				if path, ok := files[dirs[event.Wd]][name]; ok {
+					select {
+					case events <- path:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("unable to read inotify events: %w", err)
+		case path := <-events:
+			pending[path] = true
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			for path := range pending {
+				onChange(path)
+			}
+			clear(pending)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package libs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startWatch runs WatchFiles on paths until the test ends and returns the
+// changes it reports. It returns once the watch is set up, which it finds
+// out by writing the first path until a change is reported.
+func startWatch(t *testing.T, paths ...string) <-chan string {
+	t.Helper()
+
+	changes := make(chan string, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchFiles(ctx, paths, func(path string) {
+			changes <- path
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("WatchFiles() error = %v", err)
+		}
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		writeFile(t, paths[0], "probe")
+		select {
+		case <-changes:
+			return changes
+		case <-time.After(3 * watchDebounce):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watch not set up")
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitChange fails the test unless path is reported within a few seconds.
+func waitChange(t *testing.T, changes <-chan string, path string) {
+	t.Helper()
+
+	select {
+	case got := <-changes:
+		if got != path {
+			t.Errorf("change of %s, want %s", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change of %s reported", path)
+	}
+}
+
+// noChange fails the test if a change is reported in the next few
+// debounce periods.
+func noChange(t *testing.T, changes <-chan string) {
+	t.Helper()
+
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected change of %s", got)
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func TestWatchFilesDebounce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	changes := startWatch(t, path)
+
+	// An editor saving in several steps reloads once
+	for _, data := range []string{"", "{", "{}"} {
+		writeFile(t, path, data)
+	}
+	waitChange(t, changes, path)
+	noChange(t, changes)
+
+	// Other files of the directory are ignored
+	writeFile(t, filepath.Join(dir, "other.json"), "{}")
+	noChange(t, changes)
+}
+
+func TestWatchFilesRenameOver(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "style.css")
+	changes := startWatch(t, path)
+
+	tmp := filepath.Join(dir, ".style.css.swp")
+	writeFile(t, tmp, "window {}")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, changes, path)
+	noChange(t, changes)
+
+	// The file keeps being watched after it was replaced
+	writeFile(t, path, "label {}")
+	waitChange(t, changes, path)
+}
+
+func TestWatchFilesRecreate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	changes := startWatch(t, path)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, changes, path)
+
+	writeFile(t, path, "{}")
+	waitChange(t, changes, path)
+	noChange(t, changes)
+}
+
+func TestWatchFilesSeveralPaths(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	style := filepath.Join(dir, "style.css")
+	changes := startWatch(t, config, style)
+
+	// Changes to both files within the debounce period are each reported
+	writeFile(t, config, "{}")
+	writeFile(t, style, "window {}")
+
+	got := make(map[string]bool)
+	for range 2 {
+		select {
+		case path := <-changes:
+			got[path] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("changes reported = %v, want %s and %s", got, config, style)
+		}
+	}
+	if !got[config] || !got[style] {
+		t.Errorf("changes reported = %v, want %s and %s", got, config, style)
+	}
+}
+
+func TestWatchFilesMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "config.json")
+
+	if err := WatchFiles(context.Background(), []string{path}, func(string) {}); err == nil {
+		t.Error("WatchFiles() succeeded on a missing directory")
+	}
+}
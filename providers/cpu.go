@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CPUSnapshot is the CPU usage at one point in time.
+type CPUSnapshot struct {
+	// Usage is the share of CPU time spent in user space, in percent.
+	Usage float64
+}
+
+// CPU reads the CPU usage from top.
+type CPU struct{}
+
+func NewCPU() *CPU {
+	return &CPU{}
+}
+
+func (c *CPU) Poll(ctx context.Context) (CPUSnapshot, error) {
+	cmd := exec.CommandContext(ctx, "top", "-bn1")
+	output, err := cmd.Output()
+	if err != nil {
+		return CPUSnapshot{}, fmt.Errorf("unable to get CPU usage: %w", err)
+	}
+
+	usage, err := parseTop(string(output))
+	if err != nil {
+		return CPUSnapshot{}, fmt.Errorf("unable to parse CPU usage: %w", err)
+	}
+
+	return CPUSnapshot{Usage: usage}, nil
+}
+
+// parseTop returns the user space share of the summary line of top, such
+// as "%Cpu(s):  3.1 us,  1.0 sy, ...".
+func parseTop(output string) (float64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "Cpu(s)") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return 0, fmt.Errorf("invalid summary line %q", line)
+		}
+
+		return strconv.ParseFloat(fields[1], 64)
+	}
+
+	return 0, errors.New("no CPU summary line")
+}
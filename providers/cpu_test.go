@@ -0,0 +1,53 @@
+package providers
+
+import "testing"
+
+func TestParseTop(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    float64
+		wantErr bool
+	}{
+		{
+			name: "procps",
+			output: "top - 10:00:00 up 1 day,  2 users,  load average: 0.10, 0.20, 0.30\n" +
+				"Tasks: 200 total,   1 running, 199 sleeping,   0 stopped,   0 zombie\n" +
+				"%Cpu(s):  3.1 us,  1.0 sy,  0.0 ni, 95.9 id,  0.0 wa,  0.0 hi,  0.0 si,  0.0 st\n" +
+				"MiB Mem :  15921.0 total,   8000.0 free\n",
+			want: 3.1,
+		},
+		{
+			name:   "integer",
+			output: "%Cpu(s): 12 us, 1 sy\n",
+			want:   12,
+		},
+		{
+			name:    "no summary line",
+			output:  "top - 10:00:00 up 1 day\n",
+			wantErr: true,
+		},
+		{
+			name:    "truncated summary line",
+			output:  "%Cpu(s):\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			output:  "%Cpu(s): n/a us\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTop(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTop() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// DiskSnapshot is the usage of a file system at one point in time.
+type DiskSnapshot struct {
+	// Usage is the share of used space, in percent.
+	Usage float64
+}
+
+// Disk reads the usage of the file system containing path from df.
+type Disk struct {
+	path string
+}
+
+func NewDisk(path string) *Disk {
+	return &Disk{
+		path: path,
+	}
+}
+
+func (d *Disk) Poll(ctx context.Context) (DiskSnapshot, error) {
+	// -P keeps each file system on one line, however long its name
+	cmd := exec.CommandContext(ctx, "df", "-P", "--", d.path)
+	output, err := cmd.Output()
+	if err != nil {
+		return DiskSnapshot{}, fmt.Errorf("unable to get disk usage: %w", err)
+	}
+
+	usage, err := parseDF(string(output))
+	if err != nil {
+		return DiskSnapshot{}, fmt.Errorf("unable to parse disk usage: %w", err)
+	}
+
+	return DiskSnapshot{Usage: usage}, nil
+}
+
+// parseDF returns the capacity column, such as "42%", of the last line of
+// df -P.
+func parseDF(output string) (float64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, errors.New("no file system line")
+	}
+
+	line := lines[len(lines)-1]
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return 0, fmt.Errorf("invalid file system line %q", line)
+	}
+
+	return strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+}
@@ -0,0 +1,54 @@
+package providers
+
+import "testing"
+
+func TestParseDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    float64
+		wantErr bool
+	}{
+		{
+			name: "coreutils",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/nvme0n1p2   479079112 201234567 253456789      45% /\n",
+			want: 45,
+		},
+		{
+			name: "mount point with spaces",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sdb1         1000000    120000    880000      12% /media/My Disk\n",
+			want: 12,
+		},
+		{
+			name:    "header only",
+			output:  "Filesystem     1024-blocks      Used Available Capacity Mounted on\n",
+			wantErr: true,
+		},
+		{
+			name: "truncated line",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sda1 100\n",
+			wantErr: true,
+		},
+		{
+			name: "not a percentage",
+			output: "Filesystem     1024-blocks      Used Available Capacity Mounted on\n" +
+				"/dev/sda1   100  50  50  -  /\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDF(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDF() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDF() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Package providers collects the data shown by the widgets.
+//
+// Providers do not depend on GTK. Each one produces immutable snapshots,
+// either when polled or when an event arrives, and the widgets only render
+// those snapshots on the GTK thread. Polling methods of a provider are meant
+// to be called from a single goroutine at a time.
+package providers
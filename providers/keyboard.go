@@ -0,0 +1,171 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// xkbRules lists the layouts and variants known to xkb with the names
+// keyboards report them under.
+const xkbRules = "/usr/share/X11/xkb/rules/evdev.lst"
+
+// KeyboardSnapshot describes the layout of a keyboard.
+type KeyboardSnapshot struct {
+	Device string
+	Layout string // name of the active layout, e.g. "English (US)"
+	// Codes maps the names of the layouts configured for the keyboard to
+	// their xkb layout, e.g. "German (no dead keys)" to "de".
+	Codes map[string]string
+}
+
+// Keyboard follows the layout of a keyboard through Hyprland.
+type Keyboard struct {
+	hyprctl *libs.Hyprctl
+
+	rulesOnce sync.Once
+	rules     map[string]string // layout names keyed by "layout" or "layout(variant)"
+}
+
+func NewKeyboard() *Keyboard {
+	return &Keyboard{
+		hyprctl: libs.NewHyprctl(),
+	}
+}
+
+// Snapshot describes the named keyboard, or the main one if device is "".
+func (k *Keyboard) Snapshot(ctx context.Context, device string) (KeyboardSnapshot, error) {
+	devices, err := k.hyprctl.Devices(ctx)
+	if err != nil {
+		return KeyboardSnapshot{}, err
+	}
+
+	for _, kb := range devices.Keyboards {
+		if (device == "" && kb.Main) || kb.Name == device {
+			return KeyboardSnapshot{
+				Device: kb.Name,
+				Layout: kb.ActiveKeymap,
+				Codes:  layoutCodes(kb.Layout, kb.Variant, k.xkbRules()),
+			}, nil
+		}
+	}
+
+	if device == "" {
+		return KeyboardSnapshot{}, fmt.Errorf("no main keyboard found")
+	}
+	return KeyboardSnapshot{}, fmt.Errorf("keyboard %q not found", device)
+}
+
+// Next switches the named keyboard, or the main one if device is "", to
+// its next layout.
+func (k *Keyboard) Next(ctx context.Context, device string) error {
+	if device == "" {
+		device = "current"
+	}
+
+	return k.hyprctl.Dispatch(ctx, "switchxkblayout", device, "next")
+}
+
+// xkbRules returns the names of the layouts and variants known to xkb,
+// which are read once.
+func (k *Keyboard) xkbRules() map[string]string {
+	k.rulesOnce.Do(func() {
+		f, err := os.Open(xkbRules)
+		if err != nil {
+			libs.Log.Println("unable to read xkb layouts:", err)
+			return
+		}
+		defer f.Close()
+
+		k.rules = parseXKBRules(f)
+	})
+
+	return k.rules
+}
+
+// parseXKBRules reads the layouts and variants of an xkb rules listing
+// such as evdev.lst, and returns their names keyed by "layout" or
+// "layout(variant)".
+func parseXKBRules(r io.Reader) map[string]string {
+	rules := make(map[string]string)
+
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "! "); ok {
+			section = strings.TrimSpace(name)
+			continue
+		}
+
+		key, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+
+		switch section {
+		case "layout":
+			rules[key] = name
+		case "variant":
+			// Variants name their layout: "nodeadkeys  de: German (no dead keys)"
+			layout, name, ok := strings.Cut(name, ": ")
+			if ok {
+				rules[layout+"("+key+")"] = name
+			}
+		}
+	}
+
+	return rules
+}
+
+// layoutCodes maps the names of the configured layouts to their xkb
+// layout. layouts and variants are the comma separated lists of the
+// keyboard, e.g. "us,de" and ",nodeadkeys", and rules the names from
+// parseXKBRules.
+func layoutCodes(layouts, variants string, rules map[string]string) map[string]string {
+	codes := make(map[string]string)
+	if layouts == "" {
+		return codes
+	}
+
+	variantList := strings.Split(variants, ",")
+	for i, layout := range strings.Split(layouts, ",") {
+		layout = strings.TrimSpace(layout)
+
+		key := layout
+		if i < len(variantList) {
+			if variant := strings.TrimSpace(variantList[i]); variant != "" {
+				key += "(" + variant + ")"
+			}
+		}
+
+		if name, ok := rules[key]; ok {
+			codes[name] = layout
+		}
+	}
+
+	return codes
+}
+
+// LayoutCode returns the short code shown for the layout called name: its
+// xkb layout if codes has it, otherwise the first two letters of the name,
+// e.g. "fr" for "French".
+func LayoutCode(name string, codes map[string]string) string {
+	if code, ok := codes[name]; ok {
+		return code
+	}
+
+	code := []rune(strings.TrimSpace(name))
+	if len(code) > 2 {
+		code = code[:2]
+	}
+
+	return strings.ToLower(string(code))
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// evdevRules is an excerpt of evdev.lst.
+const evdevRules = `! model
+  pc105           Generic 105-key PC
+! layout
+  us              English (US)
+  de              German
+  fr              French
+! variant
+  intl            us: English (US, intl., with dead keys)
+  nodeadkeys      de: German (no dead keys)
+  mac_nodeadkeys  de: German (Macintosh, no dead keys)
+! option
+  grp                  Switching to another layout
+  grp:alt_shift_toggle Alt+Shift
+`
+
+func TestParseXKBRules(t *testing.T) {
+	want := map[string]string{
+		"us":                 "English (US)",
+		"de":                 "German",
+		"fr":                 "French",
+		"us(intl)":           "English (US, intl., with dead keys)",
+		"de(nodeadkeys)":     "German (no dead keys)",
+		"de(mac_nodeadkeys)": "German (Macintosh, no dead keys)",
+	}
+
+	if got := parseXKBRules(strings.NewReader(evdevRules)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseXKBRules() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLayoutCodes(t *testing.T) {
+	rules := parseXKBRules(strings.NewReader(evdevRules))
+
+	tests := []struct {
+		name     string
+		layouts  string
+		variants string
+		want     map[string]string
+	}{
+		{
+			name:    "single layout",
+			layouts: "us",
+			want:    map[string]string{"English (US)": "us"},
+		},
+		{
+			name:     "variants",
+			layouts:  "us,de,de",
+			variants: "intl,,mac_nodeadkeys",
+			want: map[string]string{
+				"English (US, intl., with dead keys)": "us",
+				"German":                              "de",
+				"German (Macintosh, no dead keys)":    "de",
+			},
+		},
+		{
+			name:     "fewer variants than layouts",
+			layouts:  "fr, de",
+			variants: "",
+			want:     map[string]string{"French": "fr", "German": "de"},
+		},
+		{
+			name:    "unknown layout",
+			layouts: "xx,us",
+			want:    map[string]string{"English (US)": "us"},
+		},
+		{
+			name: "no layout",
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layoutCodes(tt.layouts, tt.variants, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("layoutCodes(%q, %q) = %v, want %v", tt.layouts, tt.variants, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutCode(t *testing.T) {
+	codes := map[string]string{
+		"German (Macintosh, no dead keys)": "de",
+		"English (US)":                     "us",
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"German (Macintosh, no dead keys)", "de"},
+		{"English (US)", "us"},
+		{"French", "fr"},
+		{"  Русская", "ру"},
+		{"A", "a"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := LayoutCode(tt.name, codes); got != tt.want {
+			t.Errorf("LayoutCode(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
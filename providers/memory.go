@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MemorySnapshot is the memory usage at one point in time.
+type MemorySnapshot struct {
+	// Usage is the share of used memory, in percent.
+	Usage float64
+}
+
+// Memory reads the memory usage from free.
+type Memory struct{}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Poll(ctx context.Context) (MemorySnapshot, error) {
+	cmd := exec.CommandContext(ctx, "free")
+	output, err := cmd.Output()
+	if err != nil {
+		return MemorySnapshot{}, fmt.Errorf("unable to get memory usage: %w", err)
+	}
+
+	usage, err := parseFree(string(output))
+	if err != nil {
+		return MemorySnapshot{}, fmt.Errorf("unable to parse memory usage: %w", err)
+	}
+
+	return MemorySnapshot{Usage: usage}, nil
+}
+
+// parseFree returns the share of used memory from the "Mem:" line of free,
+// which lists the total and used memory first.
+func parseFree(output string) (float64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "Mem:" {
+			continue
+		}
+
+		if len(fields) < 3 {
+			return 0, fmt.Errorf("invalid memory line %q", line)
+		}
+
+		total, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, err
+		}
+		used, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return 0, err
+		}
+		if total <= 0 {
+			return 0, fmt.Errorf("invalid total memory %q", fields[1])
+		}
+
+		return used / total * 100, nil
+	}
+
+	return 0, errors.New("no memory line")
+}
@@ -0,0 +1,52 @@
+package providers
+
+import "testing"
+
+func TestParseFree(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    float64
+		wantErr bool
+	}{
+		{
+			name: "procps",
+			output: "               total        used        free      shared  buff/cache   available\n" +
+				"Mem:        16000000     4000000     8000000      500000     4000000    11000000\n" +
+				"Swap:        2000000           0     2000000\n",
+			want: 25,
+		},
+		{
+			name:    "no memory line",
+			output:  "Swap:        2000000           0     2000000\n",
+			wantErr: true,
+		},
+		{
+			name:    "truncated memory line",
+			output:  "Mem:        16000000\n",
+			wantErr: true,
+		},
+		{
+			name:    "zero total",
+			output:  "Mem:        0     0     0\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			output:  "Mem:        16G     4G     8G\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFree(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFree() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseFree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
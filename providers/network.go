@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NetworkSnapshot is the transfer rate of a network interface.
+type NetworkSnapshot struct {
+	// Interface is the measured interface, empty if no active interface was
+	// found.
+	Interface string
+	// HasRates is false for the first measurement of an interface, which
+	// has nothing to compare against.
+	HasRates bool
+	// RxRate and TxRate are the receive and transmit rates in KB/s.
+	RxRate float64
+	TxRate float64
+}
+
+// Network measures transfer rates from /proc/net/dev. An empty interface
+// selects the first active one.
+type Network struct {
+	interface_     string
+	prevRx, prevTx uint64
+	prevTime       time.Time
+}
+
+func NewNetwork(interface_ string) *Network {
+	return &Network{
+		interface_: interface_,
+	}
+}
+
+func (n *Network) findActiveInterface() (string, error) {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return "", fmt.Errorf("unable to read /proc/net/dev: %w", err)
+	}
+
+	for _, counters := range parseNetDev(string(data)) {
+		iface := counters.iface
+
+		// Skip loopback and virtual interfaces
+		if iface == "lo" || strings.HasPrefix(iface, "tun") || strings.HasPrefix(iface, "docker") {
+			continue
+		}
+
+		// Check if interface is up
+		upFile := fmt.Sprintf("/sys/class/net/%s/operstate", iface)
+		upData, err := os.ReadFile(upFile)
+		if err != nil {
+			continue
+		}
+
+		if strings.TrimSpace(string(upData)) == "up" {
+			return iface, nil
+		}
+	}
+
+	return "", fmt.Errorf("no active network interface found")
+}
+
+// Poll reads the interface counters and computes the transfer rates since
+// the previous poll.
+func (n *Network) Poll(ctx context.Context) (NetworkSnapshot, error) {
+	// If no interface specified or interface is down, try to find an active one
+	if n.interface_ == "" {
+		iface, err := n.findActiveInterface()
+		if err != nil {
+			return NetworkSnapshot{}, nil
+		}
+		n.interface_ = iface
+	}
+
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return NetworkSnapshot{}, fmt.Errorf("unable to read /proc/net/dev: %w", err)
+	}
+
+	for _, counters := range parseNetDev(string(data)) {
+		if counters.iface != n.interface_ {
+			continue
+		}
+
+		if counters.err != nil {
+			return NetworkSnapshot{}, counters.err
+		}
+
+		return n.measure(counters.rx, counters.tx, time.Now()), nil
+	}
+
+	return NetworkSnapshot{Interface: n.interface_}, nil
+}
+
+// measure computes the transfer rates from the byte counters of the
+// previous call.
+func (n *Network) measure(rx, tx uint64, now time.Time) NetworkSnapshot {
+	snapshot := NetworkSnapshot{Interface: n.interface_}
+
+	if n.prevRx > 0 && n.prevTx > 0 {
+		elapsed := now.Sub(n.prevTime).Seconds()
+		snapshot.HasRates = true
+		snapshot.RxRate = float64(rx-n.prevRx) / 1024 / elapsed // KB/s
+		snapshot.TxRate = float64(tx-n.prevTx) / 1024 / elapsed // KB/s
+	}
+
+	n.prevRx = rx
+	n.prevTx = tx
+	n.prevTime = now
+
+	return snapshot
+}
+
+// netDevCounters are the byte counters of an interface in /proc/net/dev.
+// err is set if they could not be parsed.
+type netDevCounters struct {
+	iface  string
+	rx, tx uint64
+	err    error
+}
+
+// parseNetDev lists the interfaces of /proc/net/dev, whose lines read
+// "iface: rx-bytes rx-packets ... tx-bytes ...". Large counters can follow
+// the colon without a space.
+func parseNetDev(data string) []netDevCounters {
+	var result []netDevCounters
+	for _, line := range strings.Split(data, "\n") {
+		// Header lines have no colon
+		iface, values, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		counters := netDevCounters{iface: strings.TrimSpace(iface)}
+
+		fields := strings.Fields(values)
+		if len(fields) < 9 {
+			counters.err = fmt.Errorf("invalid counters for %s", counters.iface)
+			result = append(result, counters)
+			continue
+		}
+
+		var err error
+		if counters.rx, err = strconv.ParseUint(fields[0], 10, 64); err != nil {
+			counters.err = fmt.Errorf("unable to parse rx bytes: %w", err)
+		} else if counters.tx, err = strconv.ParseUint(fields[8], 10, 64); err != nil {
+			counters.err = fmt.Errorf("unable to parse tx bytes: %w", err)
+		}
+
+		result = append(result, counters)
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0:  1048576    900    0    0    0     0          0         0   524288     700    0    0    0     0       0          0
+veth0a1:  2048     10    0    0    0     0          0         0     4096      20    0    0    0     0       0          0
+wlan0:12345678901  8000    0    0    0     0          0         0 98765432     6000    0    0    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []netDevCounters
+		wantErr []bool
+	}{
+		{
+			name: "interfaces",
+			data: netDev,
+			want: []netDevCounters{
+				{iface: "lo", rx: 123456, tx: 123456},
+				{iface: "eth0", rx: 1048576, tx: 524288},
+				{iface: "veth0a1", rx: 2048, tx: 4096},
+				{iface: "wlan0", rx: 12345678901, tx: 98765432},
+			},
+		},
+		{
+			name: "headers only",
+			data: "Inter-|   Receive |  Transmit\n face |bytes |bytes\n",
+		},
+		{
+			name:    "truncated counters",
+			data:    "  eth0:  1048576 900\n",
+			want:    []netDevCounters{{iface: "eth0"}},
+			wantErr: []bool{true},
+		},
+		{
+			name:    "invalid counters",
+			data:    "  eth0:  lots 900 0 0 0 0 0 0 524288 700 0 0 0 0 0 0\n",
+			want:    []netDevCounters{{iface: "eth0"}},
+			wantErr: []bool{true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseNetDev(tt.data)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseNetDev() returned %d interfaces, want %d: %+v", len(got), len(tt.want), got)
+			}
+
+			for i := range got {
+				wantErr := i < len(tt.wantErr) && tt.wantErr[i]
+				if (got[i].err != nil) != wantErr {
+					t.Errorf("interface %s: error = %v, want error %v", got[i].iface, got[i].err, wantErr)
+				}
+
+				got[i].err = nil
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("interface %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkMeasure(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		rx, tx uint64
+		at     time.Duration
+		want   NetworkSnapshot
+	}{
+		{
+			name: "first measurement",
+			rx:   10240,
+			tx:   2048,
+			want: NetworkSnapshot{Interface: "eth0"},
+		},
+		{
+			name: "two seconds later",
+			rx:   10240 + 2*4096,
+			tx:   2048 + 2*1024,
+			at:   2 * time.Second,
+			want: NetworkSnapshot{Interface: "eth0", HasRates: true, RxRate: 4, TxRate: 1},
+		},
+		{
+			name: "idle",
+			rx:   10240 + 2*4096,
+			tx:   2048 + 2*1024,
+			at:   3 * time.Second,
+			want: NetworkSnapshot{Interface: "eth0", HasRates: true},
+		},
+	}
+
+	// Each measurement is compared with the previous one, so the cases run
+	// in order on the same provider
+	n := NewNetwork("eth0")
+	for _, tt := range tests {
+		if got := n.measure(tt.rx, tt.tx, start.Add(tt.at)); got != tt.want {
+			t.Errorf("%s: measure() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// NotificationSnapshot is the state of the notification daemon.
+type NotificationSnapshot struct {
+	// Count is the number of notifications as reported by swaync.
+	Count string
+}
+
+// Notification talks to SwayNotificationCenter through swaync-client.
+type Notification struct{}
+
+func NewNotification() *Notification {
+	return &Notification{}
+}
+
+func (n *Notification) Poll(ctx context.Context) (NotificationSnapshot, error) {
+	cmd := exec.CommandContext(ctx, "swaync-client", "-c")
+	output, err := cmd.Output()
+	if err != nil {
+		return NotificationSnapshot{}, fmt.Errorf("unable to get notification count: %w", err)
+	}
+
+	return NotificationSnapshot{Count: strings.TrimSpace(string(output))}, nil
+}
+
+// Toggle opens or closes the notification center.
+func (n *Notification) Toggle(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "swaync-client", "-t")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to toggle notification center: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PlayerSnapshot is the state of the current media player.
+type PlayerSnapshot struct {
+	Status   string
+	Title    string
+	Artist   string
+	Position time.Duration
+	Duration time.Duration
+}
+
+// IsPlaying reports whether media is currently playing.
+func (s PlayerSnapshot) IsPlaying() bool {
+	return s.Status == "Playing"
+}
+
+// Player reads the state of MPRIS media players through playerctl.
+type Player struct {
+	last PlayerSnapshot
+}
+
+func NewPlayer() *Player {
+	return &Player{}
+}
+
+// Poll queries playerctl. Metadata that cannot be read keeps the value of
+// the previous poll.
+func (p *Player) Poll(ctx context.Context) (PlayerSnapshot, error) {
+	snapshot := p.last
+
+	cmd := exec.CommandContext(ctx, "playerctl", "status", "--format", "{{status}}")
+	status, err := cmd.Output()
+	if err != nil {
+		snapshot.Status = "Stopped"
+		p.last = snapshot
+		return snapshot, nil
+	}
+
+	snapshot.Status = strings.TrimSpace(string(status))
+
+	// Get metadata
+	cmd = exec.CommandContext(ctx, "playerctl", "metadata", "--format", "{{title}}|{{artist}}")
+	if metadata, err := cmd.Output(); err == nil {
+		if title, artist, ok := parseMetadata(string(metadata)); ok {
+			snapshot.Title = title
+			snapshot.Artist = artist
+		}
+	}
+
+	// Get position and duration
+	cmd = exec.CommandContext(ctx, "playerctl", "position")
+	if pos, err := cmd.Output(); err == nil {
+		if pos, err := parseDuration(string(pos), "s"); err == nil {
+			snapshot.Position = pos
+		}
+	}
+
+	cmd = exec.CommandContext(ctx, "playerctl", "metadata", "mpris:length")
+	if dur, err := cmd.Output(); err == nil {
+		if dur, err := parseDuration(string(dur), "us"); err == nil {
+			snapshot.Duration = dur
+		}
+	}
+
+	p.last = snapshot
+	return snapshot, nil
+}
+
+// parseMetadata splits the "title|artist" line printed by playerctl.
+func parseMetadata(output string) (title, artist string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(output), "|")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// parseDuration reads a number printed by playerctl in the given unit:
+// positions are in seconds, mpris:length in microseconds.
+func parseDuration(output, unit string) (time.Duration, error) {
+	return time.ParseDuration(strings.TrimSpace(output) + unit)
+}
@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantTitle  string
+		wantArtist string
+		wantOK     bool
+	}{
+		{
+			name:       "title and artist",
+			output:     "Song Title|Some Artist\n",
+			wantTitle:  "Song Title",
+			wantArtist: "Some Artist",
+			wantOK:     true,
+		},
+		{
+			name:      "no artist",
+			output:    "Podcast episode|\n",
+			wantTitle: "Podcast episode",
+			wantOK:    true,
+		},
+		{
+			name:   "no separator",
+			output: "No players found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, artist, ok := parseMetadata(tt.output)
+			if title != tt.wantTitle || artist != tt.wantArtist || ok != tt.wantOK {
+				t.Errorf("parseMetadata() = %q, %q, %v, want %q, %q, %v",
+					title, artist, ok, tt.wantTitle, tt.wantArtist, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		unit    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name:   "position in seconds",
+			output: "83.456789\n",
+			unit:   "s",
+			want:   83456789 * time.Microsecond,
+		},
+		{
+			name:   "length in microseconds",
+			output: "215000000\n",
+			unit:   "us",
+			want:   215 * time.Second,
+		},
+		{
+			name:    "empty",
+			output:  "\n",
+			unit:    "us",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			output:  "No player could handle this command\n",
+			unit:    "s",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDuration(tt.output, tt.unit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Binding is a key binding of a submap.
+type Binding struct {
+	Keys   string
+	Action string
+}
+
+// Submap looks up Hyprland submaps.
+type Submap struct {
+	hyprctl *libs.Hyprctl
+}
+
+func NewSubmap() *Submap {
+	return &Submap{
+		hyprctl: libs.NewHyprctl(),
+	}
+}
+
+// SubmapClass returns the CSS class of the named submap: "submap-"
+// followed by the name in lower case letters and digits, with dashes in
+// place of anything else.
+func SubmapClass(name string) string {
+	return "submap-" + strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, name), "-")
+}
+
+// Current returns the active submap, or "" for the default one.
+func (s *Submap) Current(ctx context.Context) (string, error) {
+	return s.hyprctl.Submap(ctx)
+}
+
+// Bindings lists the key bindings of the named submap.
+func (s *Submap) Bindings(ctx context.Context, name string) ([]Binding, error) {
+	binds, err := s.hyprctl.Binds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []Binding
+	for _, b := range binds {
+		if b.Submap != name {
+			continue
+		}
+
+		action := b.Dispatcher
+		if b.Arg != "" {
+			action += " " + b.Arg
+		}
+		if b.HasDescription {
+			action = b.Description
+		}
+
+		bindings = append(bindings, Binding{Keys: b.Keys(), Action: action})
+	}
+
+	return bindings, nil
+}
@@ -0,0 +1,22 @@
+package providers
+
+import "testing"
+
+func TestSubmapClass(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"resize", "submap-resize"},
+		{"Move Window", "submap-move-window"},
+		{"  launch: apps!", "submap-launch--apps"},
+		{"Ärger2", "submap-ärger2"},
+		{"", "submap-"},
+	}
+
+	for _, tt := range tests {
+		if got := SubmapClass(tt.name); got != tt.want {
+			t.Errorf("SubmapClass(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
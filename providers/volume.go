@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// VolumeSnapshot is the state of an audio sink.
+type VolumeSnapshot struct {
+	// Volume is the volume of the first channel, in percent.
+	Volume int
+	Muted  bool
+}
+
+// Volume reads and changes the volume of a PulseAudio or PipeWire sink
+// through pactl. An empty sink selects the default sink.
+type Volume struct {
+	sink string
+}
+
+func NewVolume(sink string) *Volume {
+	if sink == "" {
+		sink = "@DEFAULT_SINK@"
+	}
+	return &Volume{
+		sink: sink,
+	}
+}
+
+func (v *Volume) Poll(ctx context.Context) (VolumeSnapshot, error) {
+	cmd := exec.CommandContext(ctx, "pactl", "get-sink-volume", v.sink)
+	output, err := cmd.Output()
+	if err != nil {
+		return VolumeSnapshot{}, fmt.Errorf("unable to get volume: %w", err)
+	}
+
+	volume, err := parseVolume(string(output))
+	if err != nil {
+		return VolumeSnapshot{}, err
+	}
+
+	cmd = exec.CommandContext(ctx, "pactl", "get-sink-mute", v.sink)
+	output, err = cmd.Output()
+	if err != nil {
+		return VolumeSnapshot{}, fmt.Errorf("unable to get mute status: %w", err)
+	}
+
+	return VolumeSnapshot{
+		Volume: volume,
+		Muted:  parseMute(string(output)),
+	}, nil
+}
+
+// parseVolume returns the percentage of the first channel from the output
+// of pactl get-sink-volume, such as
+// "Volume: front-left: 32768 /  50% / -18.06 dB,   front-right: ...".
+func parseVolume(output string) (int, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "Volume:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		volume, err := strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse volume: %w", err)
+		}
+
+		return volume, nil
+	}
+
+	return 0, fmt.Errorf("unable to find volume in pactl output")
+}
+
+// parseMute reads the output of pactl get-sink-mute, "Mute: yes" or
+// "Mute: no".
+func parseMute(output string) bool {
+	return strings.Contains(output, "yes")
+}
+
+// ToggleMute mutes or unmutes the sink.
+func (v *Volume) ToggleMute(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "pactl", "set-sink-mute", v.sink, "toggle")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to toggle mute: %w", err)
+	}
+	return nil
+}
+
+// Adjust changes the volume by step percent, which may be negative.
+func (v *Volume) Adjust(ctx context.Context, step int) error {
+	cmd := exec.CommandContext(ctx, "pactl", "set-sink-volume", v.sink, fmt.Sprintf("%+d%%", step))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to change volume: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package providers
+
+import "testing"
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "stereo",
+			output: "Volume: front-left: 32768 /  50% / -18.06 dB,   front-right: 32768 /  50% / -18.06 dB\n" +
+				"        balance 0.00\n",
+			want: 50,
+		},
+		{
+			name:   "first channel wins",
+			output: "Volume: front-left: 65536 / 100% / 0.00 dB,   front-right: 0 /   0% / -inf dB\n",
+			want:   100,
+		},
+		{
+			name:   "boosted",
+			output: "Volume: mono: 98304 / 150% / 10.57 dB\n",
+			want:   150,
+		},
+		{
+			name:    "no volume line",
+			output:  "Failed to get sink volume: No such entity\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a percentage",
+			output:  "Volume: mono: 98304 / loud / 10.57 dB\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVolume(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVolume() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseVolume() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMute(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"Mute: yes\n", true},
+		{"Mute: no\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := parseMute(tt.output); got != tt.want {
+			t.Errorf("parseMute(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
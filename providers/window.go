@@ -0,0 +1,26 @@
+package providers
+
+import "github.com/grentenrg/go-bar/compositor"
+
+// WindowSnapshot describes the focused window.
+type WindowSnapshot struct {
+	Class string
+	Title string
+}
+
+// Window follows the focused window through the compositor state.
+type Window struct{}
+
+func NewWindow() *Window {
+	return &Window{}
+}
+
+// Snapshot describes the focused window of state.
+func (w *Window) Snapshot(state compositor.State) WindowSnapshot {
+	window := state.FocusedWindow()
+	if window == nil {
+		return WindowSnapshot{}
+	}
+
+	return WindowSnapshot{Class: window.AppID, Title: window.Title}
+}
@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grentenrg/go-bar/compositor"
+)
+
+type Workspace struct {
+	ID       int
+	Name     string
+	Monitor  string // Name of the output, "" for persistent workspaces of no output
+	IsActive bool
+	// Exists is false for persistent workspaces the compositor does not
+	// have yet.
+	Exists bool
+	// Windows is the number of windows on the workspace.
+	Windows int
+	// Urgent is set while the workspace or one of its windows demands
+	// attention, and UrgentTitles lists the titles of those windows.
+	Urgent       bool
+	UrgentTitles []string
+	// Apps lists the app IDs of the windows on the workspace, each once, in
+	// the order of their first window.
+	Apps []string
+}
+
+// WorkspacesSnapshot lists the workspaces of the compositor.
+type WorkspacesSnapshot struct {
+	Workspaces      []Workspace
+	ActiveWorkspace string
+	ActiveMonitor   string // Current monitor name
+}
+
+// Workspaces lists workspaces from the compositor state.
+type Workspaces struct{}
+
+func NewWorkspaces() *Workspaces {
+	return &Workspaces{}
+}
+
+// ParseWorkspaceList parses a comma separated list of workspace names and
+// ranges of numbered workspaces, e.g. "1-5,web". Workspaces listed twice
+// are kept at their first position.
+func ParseWorkspaceList(spec string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(item, "-")
+		if !isRange {
+			add(item)
+			continue
+		}
+
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		to, err := strconv.Atoi(strings.TrimSpace(last))
+		if err != nil || to < from {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+
+		for id := from; id <= to; id++ {
+			add(strconv.Itoa(id))
+		}
+	}
+
+	return names, nil
+}
+
+// Snapshot lists the workspaces of state. If output is not "", only the
+// workspaces of the output with that name or ID are listed. The persistent
+// workspaces are listed too when the compositor does not have them, on the
+// selected output, or on every output if none is selected. Numbered
+// persistent workspaces match workspaces by number, so "2" stands for
+// "2:web" on sway.
+//
+// Workspaces are sorted by ID, with named workspaces last.
+func (w *Workspaces) Snapshot(state compositor.State, output string, persistent []string) WorkspacesSnapshot {
+	var snapshot WorkspacesSnapshot
+	if ws := state.FocusedWorkspace(); ws != nil {
+		snapshot.ActiveWorkspace = ws.Name
+	}
+	if o := state.FocusedOutput(); o != nil {
+		snapshot.ActiveMonitor = o.Name
+	}
+
+	if output != "" {
+		o := state.Output(output)
+		if o == nil {
+			return snapshot
+		}
+		output = o.Name
+	}
+
+	type key struct{ output, workspace string }
+	apps := make(map[key][]string)
+	windows := make(map[key]int)
+	urgent := make(map[key][]string)
+	for _, window := range state.Windows {
+		k := key{window.Output, window.Workspace}
+		windows[k]++
+		if window.Urgent {
+			urgent[k] = append(urgent[k], window.Title)
+		}
+		if window.AppID != "" && !slices.Contains(apps[k], window.AppID) {
+			apps[k] = append(apps[k], window.AppID)
+		}
+	}
+
+	type number struct {
+		output string
+		id     int
+	}
+	listed := make(map[key]bool)
+	numbered := make(map[number]bool)
+	for _, ws := range state.Workspaces {
+		if output != "" && ws.Output != output {
+			continue
+		}
+
+		k := key{ws.Output, ws.Name}
+		snapshot.Workspaces = append(snapshot.Workspaces, Workspace{
+			ID:       ws.ID,
+			Name:     ws.Name,
+			Monitor:  ws.Output,
+			IsActive: ws.Focused,
+			Exists:   true,
+			Windows:  windows[k],
+			Apps:     apps[k],
+
+			Urgent:       ws.Urgent || len(urgent[k]) > 0,
+			UrgentTitles: urgent[k],
+		})
+		listed[k] = true
+		if ws.ID > 0 {
+			numbered[number{ws.Output, ws.ID}] = true
+		}
+	}
+
+	outputs := []string{output}
+	if output == "" && len(state.Outputs) > 0 {
+		outputs = outputs[:0]
+		for _, o := range state.Outputs {
+			outputs = append(outputs, o.Name)
+		}
+	}
+
+	for _, o := range outputs {
+		for _, name := range persistent {
+			// Named workspaces get no ID, like on Hyprland
+			id, _ := strconv.Atoi(name)
+			if listed[key{o, name}] || (id > 0 && numbered[number{o, id}]) {
+				continue
+			}
+
+			snapshot.Workspaces = append(snapshot.Workspaces, Workspace{
+				ID:      id,
+				Name:    name,
+				Monitor: o,
+			})
+			listed[key{o, name}] = true
+			if id > 0 {
+				numbered[number{o, id}] = true
+			}
+		}
+	}
+
+	sort.SliceStable(snapshot.Workspaces, func(i, j int) bool {
+		a, b := snapshot.Workspaces[i], snapshot.Workspaces[j]
+		if (a.ID > 0) != (b.ID > 0) {
+			return a.ID > 0
+		}
+		if a.ID > 0 && a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Name < b.Name
+	})
+
+	return snapshot
+}
+
+// Switch focuses a workspace of c, which creates it if it does not exist,
+// whether it is a persistent workspace or one that was closed since the
+// snapshot.
+func (w *Workspaces) Switch(ctx context.Context, c compositor.Compositor, target Workspace) error {
+	for _, ws := range c.State().Workspaces {
+		if ws.Name == target.Name && ws.Output == target.Monitor {
+			return c.SwitchWorkspace(ctx, ws)
+		}
+	}
+
+	return c.SwitchWorkspace(ctx, compositor.Workspace{
+		ID:     target.ID,
+		Name:   target.Name,
+		Output: target.Monitor,
+	})
+}
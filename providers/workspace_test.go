@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/grentenrg/go-bar/compositor"
+)
+
+// twoOutputs has workspaces 1 and 2 on DP-1, where 2 has focus, and the
+// named workspace "web" on HDMI-1.
+var twoOutputs = compositor.State{
+	Outputs: []compositor.Output{
+		{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: "2"},
+		{ID: 1, Name: "HDMI-1", X: 1920, ActiveWorkspace: "web"},
+	},
+	Workspaces: []compositor.Workspace{
+		{ID: 2, Name: "2", Output: "DP-1", Focused: true, Visible: true},
+		{ID: -1, Name: "web", Output: "HDMI-1", Visible: true},
+		{ID: 1, Name: "1", Output: "DP-1", Urgent: true},
+	},
+	Windows: []compositor.Window{
+		{ID: "a", AppID: "kitty", Title: "shell", Workspace: "2", Output: "DP-1", Focused: true},
+		{ID: "b", AppID: "kitty", Title: "logs", Workspace: "2", Output: "DP-1"},
+		{ID: "c", AppID: "firefox", Title: "news", Workspace: "web", Output: "HDMI-1", Urgent: true},
+		{ID: "d", Title: "untitled", Workspace: "web", Output: "HDMI-1"},
+	},
+}
+
+func TestWorkspacesSnapshot(t *testing.T) {
+	ws1 := Workspace{ID: 1, Name: "1", Monitor: "DP-1", Exists: true, Urgent: true}
+	ws2 := Workspace{ID: 2, Name: "2", Monitor: "DP-1", IsActive: true, Exists: true, Windows: 2, Apps: []string{"kitty"}}
+	web := Workspace{ID: -1, Name: "web", Monitor: "HDMI-1", Exists: true, Windows: 2, Apps: []string{"firefox"},
+		Urgent: true, UrgentTitles: []string{"news"}}
+
+	tests := []struct {
+		name       string
+		state      compositor.State
+		output     string
+		persistent []string
+		want       []Workspace
+	}{
+		{
+			name:  "every output, named workspaces last",
+			state: twoOutputs,
+			want:  []Workspace{ws1, ws2, web},
+		},
+		{
+			name:   "output by name",
+			state:  twoOutputs,
+			output: "HDMI-1",
+			want:   []Workspace{web},
+		},
+		{
+			name:   "output by ID",
+			state:  twoOutputs,
+			output: "0",
+			want:   []Workspace{ws1, ws2},
+		},
+		{
+			name:   "unknown output",
+			state:  twoOutputs,
+			output: "DP-2",
+		},
+		{
+			name:       "persistent workspaces on the selected output",
+			state:      twoOutputs,
+			output:     "DP-1",
+			persistent: []string{"1", "2", "3", "mail"},
+			want: []Workspace{
+				ws1,
+				ws2,
+				{ID: 3, Name: "3", Monitor: "DP-1"},
+				{Name: "mail", Monitor: "DP-1"},
+			},
+		},
+		{
+			name:       "persistent workspaces on every output",
+			state:      twoOutputs,
+			persistent: []string{"web", "4"},
+			want: []Workspace{
+				ws1,
+				ws2,
+				{ID: 4, Name: "4", Monitor: "DP-1"},
+				{ID: 4, Name: "4", Monitor: "HDMI-1"},
+				web,
+				{Name: "web", Monitor: "DP-1"},
+			},
+		},
+		{
+			name:       "persistent numbers on every output",
+			state:      twoOutputs,
+			persistent: []string{"1", "2"},
+			want: []Workspace{
+				ws1,
+				{ID: 1, Name: "1", Monitor: "HDMI-1"},
+				ws2,
+				{ID: 2, Name: "2", Monitor: "HDMI-1"},
+				web,
+			},
+		},
+		{
+			name: "persistent workspaces by number",
+			state: compositor.State{
+				Outputs: []compositor.Output{
+					{ID: 0, Name: "DP-1", Focused: true, ActiveWorkspace: "2:web"},
+				},
+				Workspaces: []compositor.Workspace{
+					{ID: 2, Name: "2:web", Output: "DP-1", Focused: true},
+				},
+			},
+			persistent: []string{"1", "2", "2:web"},
+			want: []Workspace{
+				{ID: 1, Name: "1", Monitor: "DP-1"},
+				{ID: 2, Name: "2:web", Monitor: "DP-1", IsActive: true, Exists: true},
+			},
+		},
+		{
+			name:       "no compositor",
+			persistent: []string{"1"},
+			want:       []Workspace{{ID: 1, Name: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewWorkspaces().Snapshot(tt.state, tt.output, tt.persistent)
+			if !reflect.DeepEqual(got.Workspaces, tt.want) {
+				t.Errorf("Snapshot() workspaces =\n%+v\nwant\n%+v", got.Workspaces, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspacesSnapshotFocus(t *testing.T) {
+	got := NewWorkspaces().Snapshot(twoOutputs, "HDMI-1", nil)
+
+	if got.ActiveWorkspace != "2" || got.ActiveMonitor != "DP-1" {
+		t.Errorf("Snapshot() focus = %q on %q, want %q on %q", got.ActiveWorkspace, got.ActiveMonitor, "2", "DP-1")
+	}
+}
+
+// fakeCompositor records the workspaces it is asked to switch to.
+type fakeCompositor struct {
+	state    compositor.State
+	switched []compositor.Workspace
+}
+
+func (f *fakeCompositor) Name() string                      { return "fake" }
+func (f *fakeCompositor) Watch(w compositor.Watcher) func() { return func() {} }
+func (f *fakeCompositor) State() compositor.State           { return f.state }
+func (f *fakeCompositor) SwitchWorkspace(ctx context.Context, ws compositor.Workspace) error {
+	f.switched = append(f.switched, ws)
+	return nil
+}
+
+func TestWorkspacesSwitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Workspace
+		want   compositor.Workspace
+	}{
+		{
+			name:   "existing workspace",
+			target: Workspace{ID: -1, Name: "web", Monitor: "HDMI-1", Exists: true},
+			want:   compositor.Workspace{ID: -1, Name: "web", Output: "HDMI-1", Visible: true},
+		},
+		{
+			name:   "persistent workspace",
+			target: Workspace{ID: 3, Name: "3", Monitor: "DP-1"},
+			want:   compositor.Workspace{ID: 3, Name: "3", Output: "DP-1"},
+		},
+		{
+			name:   "workspace closed since the snapshot",
+			target: Workspace{ID: 5, Name: "5", Monitor: "DP-1", Exists: true},
+			want:   compositor.Workspace{ID: 5, Name: "5", Output: "DP-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeCompositor{state: twoOutputs}
+			if err := NewWorkspaces().Switch(context.Background(), c, tt.target); err != nil {
+				t.Fatalf("Switch() error = %v", err)
+			}
+
+			want := []compositor.Workspace{tt.want}
+			if !reflect.DeepEqual(c.switched, want) {
+				t.Errorf("Switch() switched to %+v, want %+v", c.switched, want)
+			}
+		})
+	}
+}
+
+func TestParseWorkspaceList(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{spec: "", want: nil},
+		{spec: "web", want: []string{"web"}},
+		{spec: "1-3, web ,5", want: []string{"1", "2", "3", "web", "5"}},
+		{spec: " 8 - 10 ", want: []string{"8", "9", "10"}},
+		{spec: "4-4", want: []string{"4"}},
+		{spec: ",,1,", want: []string{"1"}},
+		{spec: "1-3,2,web,web,3-4", want: []string{"1", "2", "3", "web", "4"}},
+		{spec: "3-1", wantErr: `invalid range "3-1"`},
+		{spec: "a-3", wantErr: `invalid range "a-3"`},
+		{spec: "1-", wantErr: `invalid range "1-"`},
+		{spec: "1,dev-tools", wantErr: `invalid range "dev-tools"`},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseWorkspaceList(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseWorkspaceList(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseWorkspaceList(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseWorkspaceList(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/grentenrg/go-bar/config"
+)
+
+// styleFileName is the name of the stylesheet in the go-bar configuration
+// directories.
+const styleFileName = "style.css"
+
+// defaultStyle is the theme used when no stylesheet is found.
+//
+//go:embed style.css
+var defaultStyle string
+
+// resources locates the configuration file and the stylesheet. Paths given
+// on the command line win over the XDG configuration directories; the
+// default layout and the embedded theme are used when nothing is found.
+type resources struct {
+	configPath string // from --config
+	stylePath  string // from --style
+}
+
+// configFile returns the configuration file to load, or "" for the default
+// layout.
+func (r resources) configFile() (string, error) {
+	if r.configPath != "" {
+		return r.configPath, nil
+	}
+
+	return findOptional(config.FileName)
+}
+
+// styleFile returns the stylesheet to load, or "" for the embedded theme.
+func (r resources) styleFile() (string, error) {
+	if r.stylePath != "" {
+		return r.stylePath, nil
+	}
+
+	return findOptional(styleFileName)
+}
+
+// loadConfig reads the configuration file.
+func (r resources) loadConfig() (*config.Config, error) {
+	path, err := r.configFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return config.Default(), nil
+	}
+
+	return config.LoadFile(path)
+}
+
+// loadStyle returns the contents of the stylesheet.
+func (r resources) loadStyle() (string, error) {
+	path, err := r.styleFile()
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		return defaultStyle, nil
+	}
+
+	css, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(css), nil
+}
+
+// watched returns the files whose creation, change or removal affects the
+// configuration and the stylesheet: the explicit paths, and otherwise every
+// candidate in the existing XDG configuration directories.
+func (r resources) watched() (configs, styles []string) {
+	candidates := func(explicit, name string) []string {
+		if explicit != "" {
+			path, err := filepath.Abs(explicit)
+			if err != nil {
+				return nil
+			}
+			return []string{path}
+		}
+
+		dirs, err := config.Dirs()
+		if err != nil {
+			return nil
+		}
+
+		var paths []string
+		for _, dir := range dirs {
+			// Only existing directories can be watched
+			if _, err := os.Stat(dir); err == nil {
+				paths = append(paths, filepath.Join(dir, name))
+			}
+		}
+		return paths
+	}
+
+	return candidates(r.configPath, config.FileName), candidates(r.stylePath, styleFileName)
+}
+
+// findOptional is config.Find returning "" instead of an error for missing
+// files.
+func findOptional(name string) (string, error) {
+	path, err := config.Find(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+
+	return path, err
+}
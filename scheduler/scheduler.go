@@ -0,0 +1,204 @@
+// Package scheduler runs the periodic data collection of widgets.
+//
+// Every job runs in its own worker goroutine, on ticks aligned to multiples
+// of its interval on the wall clock, so a job running every second wakes up
+// on second boundaries and jobs with related intervals wake up together.
+// What a run produces is handed to the GTK main thread through a dispatch
+// function; if the main thread has not caught up with the previous result
+// of a job yet, the older result is dropped in favour of the newer one.
+//
+// A failing job is retried with exponential backoff instead of on its
+// regular ticks until it succeeds again.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// Job is a unit of periodic work.
+type Job struct {
+	// Name identifies the job in error messages.
+	Name string
+	// Interval is the time between two runs.
+	Interval time.Duration
+	// Run collects data away from the GTK main thread and returns a function
+	// that applies the result on the main thread. It may return a nil
+	// function when there is nothing to apply.
+	Run func(ctx context.Context) (apply func(), err error)
+	// Report, if set, is called on the main thread with the error of a
+	// failed run, and with nil once the job succeeds again.
+	Report func(err error)
+}
+
+// maxRetryDelay caps the backoff of failing jobs.
+const maxRetryDelay = time.Minute
+
+// Scheduler runs jobs and delivers their results through dispatch, which
+// must call its argument on the GTK main thread, e.g. through glib.IdleAdd.
+type Scheduler struct {
+	dispatch func(func())
+}
+
+func New(dispatch func(func())) *Scheduler {
+	return &Scheduler{
+		dispatch: dispatch,
+	}
+}
+
+// Handle controls a scheduled job.
+type Handle struct {
+	job     Job
+	cancel  context.CancelFunc
+	done    chan struct{}
+	refresh chan struct{}
+
+	mu      sync.Mutex
+	pending func()
+	queued  bool
+	removed bool
+}
+
+// Add schedules job. The first run happens right away, the following ones on
+// the next multiples of the job's interval. The job runs until ctx is done or
+// the returned handle is removed.
+func (s *Scheduler) Add(ctx context.Context, job Job) *Handle {
+	ctx, cancel := context.WithCancel(ctx)
+
+	h := &Handle{
+		job:     job,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		refresh: make(chan struct{}, 1),
+	}
+
+	go s.run(ctx, h)
+
+	return h
+}
+
+// Refresh runs the job as soon as possible instead of waiting for its next
+// tick.
+func (h *Handle) Refresh() {
+	select {
+	case h.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Remove stops the job and waits for a run in progress to finish. Results
+// that were not applied yet are discarded.
+func (h *Handle) Remove() {
+	h.mu.Lock()
+	h.removed = true
+	h.pending = nil
+	h.mu.Unlock()
+
+	h.cancel()
+	<-h.done
+}
+
+func (s *Scheduler) run(ctx context.Context, h *Handle) {
+	defer close(h.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	backoff := libs.Backoff{
+		Min: h.job.Interval,
+		Max: max(h.job.Interval, maxRetryDelay),
+	}
+	failing := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-h.refresh:
+		}
+
+		apply, err := h.job.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			libs.Log.Printf("unable to update %s: %v", h.job.Name, err)
+			s.report(h, err)
+			failing = true
+
+			timer.Reset(backoff.Next())
+			continue
+		}
+
+		if failing {
+			s.report(h, nil)
+			failing = false
+			backoff.Reset()
+		}
+
+		if apply != nil {
+			s.deliver(h, apply)
+		}
+
+		timer.Reset(untilNextTick(time.Now(), h.job.Interval))
+	}
+}
+
+// deliver queues apply on the main thread, replacing a result of the same
+// job that is still waiting there.
+func (s *Scheduler) deliver(h *Handle, apply func()) {
+	h.mu.Lock()
+	h.pending = apply
+	if h.queued {
+		h.mu.Unlock()
+		return
+	}
+	h.queued = true
+	h.mu.Unlock()
+
+	s.dispatch(func() {
+		h.mu.Lock()
+		apply := h.pending
+		h.pending = nil
+		h.queued = false
+		removed := h.removed
+		h.mu.Unlock()
+
+		if apply != nil && !removed {
+			apply()
+		}
+	})
+}
+
+// report passes the outcome of a run to the job's Report function on the
+// main thread.
+func (s *Scheduler) report(h *Handle, err error) {
+	if h.job.Report == nil {
+		return
+	}
+
+	s.dispatch(func() {
+		h.mu.Lock()
+		removed := h.removed
+		h.mu.Unlock()
+
+		if !removed {
+			h.job.Report(err)
+		}
+	})
+}
+
+// untilNextTick returns the time from now until the next multiple of
+// interval.
+func untilNextTick(now time.Time, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return now.Truncate(interval).Add(interval).Sub(now)
+}
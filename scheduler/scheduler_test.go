@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitGoroutines fails the test unless the number of goroutines drops back
+// to baseline within a few seconds. Goroutines exit shortly after the
+// contexts they watch are cancelled, so the count is polled.
+func waitGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Fatalf("%d goroutines running, want %d:\n%s", n, baseline, buf)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testJobs returns jobs covering the states a worker can be stopped in:
+// waiting for its next tick, in the middle of a run and backing off after
+// a failure. started counts the runs that began.
+func testJobs(started *atomic.Int32) []Job {
+	return []Job{
+		{
+			Name:     "idle",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) (func(), error) {
+				started.Add(1)
+				return func() {}, nil
+			},
+		},
+		{
+			Name:     "busy",
+			Interval: time.Millisecond,
+			Run: func(ctx context.Context) (func(), error) {
+				started.Add(1)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		{
+			Name:     "failing",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) (func(), error) {
+				started.Add(1)
+				return nil, errors.New("no data")
+			},
+			Report: func(err error) {},
+		},
+	}
+}
+
+// waitStarted waits until n runs began.
+func waitStarted(t *testing.T, started *atomic.Int32, n int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for started.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d runs started, want %d", started.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNoGoroutineLeaks(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(cancel context.CancelFunc, handles []*Handle)
+	}{
+		{
+			name: "remove",
+			stop: func(cancel context.CancelFunc, handles []*Handle) {
+				for _, h := range handles {
+					h.Remove()
+				}
+			},
+		},
+		{
+			name: "cancel",
+			stop: func(cancel context.CancelFunc, handles []*Handle) {
+				cancel()
+			},
+		},
+		{
+			name: "cancel then remove",
+			stop: func(cancel context.CancelFunc, handles []*Handle) {
+				cancel()
+				for _, h := range handles {
+					h.Remove()
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s := New(func(f func()) {
+				go f()
+			})
+
+			var started atomic.Int32
+			jobs := testJobs(&started)
+
+			var handles []*Handle
+			for _, job := range jobs {
+				handles = append(handles, s.Add(ctx, job))
+			}
+			waitStarted(t, &started, int32(len(jobs)))
+
+			// Refreshing a stopped job must not block either
+			tt.stop(cancel, handles)
+			for _, h := range handles {
+				h.Refresh()
+			}
+
+			waitGoroutines(t, baseline)
+		})
+	}
+}
+
+func TestRemoveDiscardsPendingResults(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	// The main thread never runs, so results stay queued
+	var queued []func()
+	s := New(func(f func()) {
+		queued = append(queued, f)
+	})
+
+	applied := false
+	ran := make(chan struct{})
+	h := s.Add(context.Background(), Job{
+		Name:     "result",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (func(), error) {
+			close(ran)
+			return func() { applied = true }, nil
+		},
+	})
+
+	<-ran
+	h.Remove()
+	waitGoroutines(t, baseline)
+
+	for _, f := range queued {
+		f()
+	}
+	if applied {
+		t.Error("result applied after Remove")
+	}
+}
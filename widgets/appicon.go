@@ -0,0 +1,112 @@
+package widgets
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// appIconSize is the size of application icons, in pixels.
+const appIconSize = 16
+
+// fallbackAppIcon is shown for applications without an icon.
+const fallbackAppIcon = "application-x-executable"
+
+// appIcon returns a widget showing an application. Icons are looked up in
+// overrides, which maps app IDs to icon names, paths or glyphs, then in the
+// icon theme and finally in the desktop entry of the application. Icons
+// that fail to load, such as stale paths, give way to the next candidate,
+// the generic application icon and finally the initial of the app ID.
+func appIcon(appID string, overrides map[string]string) (gtk.IWidget, error) {
+	theme, err := gtk.IconThemeGetDefault()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get icon theme: %w", err)
+	}
+
+	var candidates []string
+	for _, id := range []string{appID, strings.ToLower(appID)} {
+		if icon, ok := overrides[id]; ok {
+			if !filepath.IsAbs(icon) && !theme.HasIcon(icon) {
+				// Neither a path nor an icon name, so a glyph such as ""
+				return iconLabel(icon)
+			}
+
+			candidates = append(candidates, icon)
+			break
+		}
+	}
+
+	candidates = append(candidates, appID, strings.ToLower(appID))
+	if icon := libs.DesktopIcon(appID); icon != "" {
+		candidates = append(candidates, icon)
+	}
+	candidates = append(candidates, fallbackAppIcon)
+
+	for _, icon := range candidates {
+		if !filepath.IsAbs(icon) && !theme.HasIcon(icon) {
+			continue
+		}
+
+		image, err := iconImage(icon)
+		if err == nil {
+			return image, nil
+		}
+		libs.Log.Println(err)
+	}
+
+	initial := ""
+	for _, r := range appID {
+		initial = string(unicode.ToUpper(r))
+		break
+	}
+
+	return iconLabel(initial)
+}
+
+// iconImage loads an icon by name, or from a file if icon is a path.
+func iconImage(icon string) (gtk.IWidget, error) {
+	var image *gtk.Image
+	if filepath.IsAbs(icon) {
+		pixbuf, err := gdk.PixbufNewFromFileAtSize(icon, appIconSize, appIconSize)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load icon %s: %w", icon, err)
+		}
+
+		image, err = gtk.ImageNewFromPixbuf(pixbuf)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create image: %w", err)
+		}
+	} else {
+		var err error
+		image, err = gtk.ImageNewFromIconName(icon, gtk.ICON_SIZE_MENU)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create image: %w", err)
+		}
+		image.SetPixelSize(appIconSize)
+	}
+
+	if styleContext, err := image.GetStyleContext(); err == nil {
+		styleContext.AddClass("app-icon")
+	}
+
+	return image, nil
+}
+
+// iconLabel shows a glyph in place of an icon.
+func iconLabel(glyph string) (gtk.IWidget, error) {
+	label, err := gtk.LabelNew(glyph)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create label: %w", err)
+	}
+
+	if styleContext, err := label.GetStyleContext(); err == nil {
+		styleContext.AddClass("app-icon")
+	}
+
+	return label, nil
+}
@@ -1,17 +1,26 @@
 package widgets
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
 )
 
 type Clock struct {
+	Lifecycle
 	box   *gtk.Box
 	label *gtk.Label
 }
 
+func init() {
+	Register("clock", func(opts *config.Options) (Widget, error) {
+		return NewClock(), nil
+	})
+}
+
 func NewClock() *Clock {
 	return &Clock{}
 }
@@ -35,11 +44,28 @@ func (c *Clock) Create() error {
 	return nil
 }
 
-func (c *Clock) Render() error {
-	c.label.SetText(time.Now().Format("15:04:05Z07"))
+func (c *Clock) Start(ctx context.Context) error {
 	return nil
 }
 
+func (c *Clock) Destroy() {
+	c.Stop()
+	c.box.Destroy()
+}
+
+func (c *Clock) Interval() time.Duration {
+	return time.Second
+}
+
+// Poll returns a function showing the current time. The scheduler runs it on
+// second boundaries.
+func (c *Clock) Poll(ctx context.Context) (func(), error) {
+	now := time.Now()
+	return func() {
+		c.label.SetText(now.Format("15:04:05Z07"))
+	}, nil
+}
+
 func (c *Clock) Name() string {
 	return "clock"
 }
@@ -1,18 +1,27 @@
 package widgets
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
 )
 
 type Date struct {
+	Lifecycle
 	label      *gtk.Label
 	box        *gtk.Box
 	lastChange time.Time
 }
 
+func init() {
+	Register("date", func(opts *config.Options) (Widget, error) {
+		return NewDate(), nil
+	})
+}
+
 func NewDate() *Date {
 	return &Date{}
 }
@@ -35,14 +44,31 @@ func (c *Date) Create() error {
 	return nil
 }
 
-func (c *Date) Render() error {
-	if time.Now().Format("02") == c.lastChange.Format("02") {
-		return nil
+func (c *Date) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *Date) Destroy() {
+	c.Stop()
+	c.box.Destroy()
+}
+
+func (c *Date) Interval() time.Duration {
+	return time.Minute
+}
+
+// Poll returns a function showing the current date when the day changed
+// since the last update.
+func (c *Date) Poll(ctx context.Context) (func(), error) {
+	now := time.Now()
+	if now.Format("02") == c.lastChange.Format("02") {
+		return nil, nil
 	}
 
-	c.label.SetText(time.Now().Format("Monday 02 January 2006"))
-	c.lastChange = time.Now()
-	return nil
+	c.lastChange = now
+	return func() {
+		c.label.SetText(now.Format("Monday 02 January 2006"))
+	}, nil
 }
 
 func (c *Date) Name() string {
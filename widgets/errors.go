@@ -0,0 +1,110 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/libs"
+)
+
+// ErrorClass is the CSS class of a widget box in its error state.
+const ErrorClass = "error"
+
+// errorMarker is the text shown next to the content of a failing widget.
+const errorMarker = "⚠"
+
+// errorMarkers holds the marker label of every box in its error state. It is
+// only accessed on the GTK thread.
+var errorMarkers = make(map[*gtk.Box]*gtk.Label)
+
+// ShowError puts a widget box into its error state: the error CSS class, a
+// short marker and the error message in the tooltip. It must be called on
+// the GTK thread.
+func ShowError(box *gtk.Box, err error) {
+	styleContext, styleErr := box.GetStyleContext()
+	if styleErr == nil {
+		styleContext.AddClass(ErrorClass)
+	}
+
+	box.SetTooltipText(err.Error())
+
+	if _, ok := errorMarkers[box]; ok {
+		return
+	}
+
+	marker, labelErr := gtk.LabelNew(errorMarker)
+	if labelErr != nil {
+		return
+	}
+
+	if markerStyle, err := marker.GetStyleContext(); err == nil {
+		markerStyle.AddClass("error-marker")
+	}
+
+	box.PackEnd(marker, false, false, 0)
+	marker.Show()
+	errorMarkers[box] = marker
+}
+
+// ClearError takes a widget box out of its error state. It must be called
+// on the GTK thread.
+func ClearError(box *gtk.Box) {
+	marker, ok := errorMarkers[box]
+	if !ok {
+		return
+	}
+
+	delete(errorMarkers, box)
+	marker.Destroy()
+
+	if styleContext, err := box.GetStyleContext(); err == nil {
+		styleContext.RemoveClass(ErrorClass)
+	}
+
+	box.SetProperty("has-tooltip", false)
+}
+
+// ErrorText returns the message shown by a widget box in its error state,
+// or "" if the box is not in its error state. It must be called on the GTK
+// thread.
+func ErrorText(box *gtk.Box) string {
+	if _, ok := errorMarkers[box]; !ok {
+		return ""
+	}
+
+	text, err := box.GetTooltipText()
+	if err != nil {
+		return ""
+	}
+
+	return text
+}
+
+// Retry calls fn until ctx is done. When fn fails, report is called with the
+// error and the next attempt waits with exponential backoff; when fn returns
+// nil, for instance because a connection was closed, it is called again
+// after the shortest delay.
+func Retry(ctx context.Context, fn func(ctx context.Context) error, report func(err error)) {
+	backoff := libs.Backoff{Min: time.Second, Max: time.Minute}
+
+	for {
+		err := fn(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			report(fmt.Errorf("%w (retrying)", err))
+		} else {
+			backoff.Reset()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Next()):
+		}
+	}
+}
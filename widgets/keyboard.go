@@ -0,0 +1,178 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/providers"
+)
+
+// Keyboard shows the active layout of a keyboard and switches to the next
+// layout on click. Layouts are shown with the label configured for their
+// name, e.g. {"English (US)": "us"}, or else with their xkb layout code.
+// Layouts are queried from Hyprland; under other compositors the widget
+// shows an empty, inactive button.
+type Keyboard struct {
+	Lifecycle
+	box      *gtk.Box
+	button   *gtk.Button
+	provider *providers.Keyboard
+	device   string // "" tracks the main keyboard
+	labels   map[string]string
+
+	// Accessed on the GTK thread only
+	ctx       context.Context
+	hyprland  bool
+	tracked   string            // name of the tracked keyboard, once known
+	codes     map[string]string // xkb layouts of the tracked keyboard
+	destroyed bool
+}
+
+func init() {
+	Register("keyboard", func(opts *config.Options) (Widget, error) {
+		device, err := opts.String("device", "")
+		if err != nil {
+			return nil, err
+		}
+
+		labels, err := opts.StringMap("labels")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewKeyboard(device, labels), nil
+	})
+}
+
+func NewKeyboard(device string, labels map[string]string) *Keyboard {
+	return &Keyboard{
+		provider: providers.NewKeyboard(),
+		device:   device,
+		labels:   labels,
+		tracked:  device,
+	}
+}
+
+func (k *Keyboard) Create() error {
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		return fmt.Errorf("unable to create box: %w", err)
+	}
+
+	k.box = box
+
+	button, err := gtk.ButtonNewWithLabel("")
+	if err != nil {
+		return fmt.Errorf("unable to create button: %w", err)
+	}
+
+	button.Connect("clicked", func() {
+		k.Go(k.ctx, func(ctx context.Context) {
+			if err := k.provider.Next(ctx, k.device); err != nil {
+				libs.Log.Println("unable to switch keyboard layout:", err)
+			}
+		})
+	})
+
+	box.PackStart(button, true, true, 0)
+
+	k.button = button
+	return nil
+}
+
+func (k *Keyboard) SetCompositor(kind string) {
+	k.hyprland = kind == compositor.KindHyprland
+}
+
+func (k *Keyboard) Start(ctx context.Context) error {
+	k.ctx = ctx
+	k.destroyed = false
+
+	k.button.SetSensitive(k.hyprland)
+	if !k.hyprland {
+		k.render("")
+		return nil
+	}
+
+	k.Resync()
+	return nil
+}
+
+func (k *Keyboard) Destroy() {
+	k.destroyed = true
+	k.Stop()
+	k.box.Destroy()
+}
+
+func (k *Keyboard) Events() []string {
+	return []string{"activelayout"}
+}
+
+func (k *Keyboard) HandleEvent(ev libs.Event) {
+	decoded, err := ev.Decode()
+	if err != nil {
+		libs.Log.Println("unable to decode activelayout event:", err)
+		return
+	}
+
+	layout, ok := decoded.(libs.ActiveLayoutEvent)
+	if !ok {
+		return
+	}
+
+	// Until the main keyboard is known, follow whichever keyboard changed
+	if k.tracked != "" && layout.Keyboard != k.tracked {
+		return
+	}
+
+	k.render(layout.Layout)
+}
+
+// Resync asks Hyprland for the layout of the tracked keyboard, as layout
+// events may have been missed.
+func (k *Keyboard) Resync() {
+	k.Go(k.ctx, func(ctx context.Context) {
+		snapshot, err := k.provider.Snapshot(ctx, k.device)
+		if err != nil {
+			libs.Log.Println("unable to query keyboard layout:", err)
+			return
+		}
+
+		glib.IdleAdd(func() {
+			if k.destroyed {
+				return
+			}
+
+			k.tracked = snapshot.Device
+			k.codes = snapshot.Codes
+			k.render(snapshot.Layout)
+		})
+	})
+}
+
+func (k *Keyboard) render(layout string) {
+	k.button.SetLabel(k.label(layout))
+	k.button.SetTooltipText(layout)
+}
+
+// label returns the text shown for a layout name.
+func (k *Keyboard) label(layout string) string {
+	if label, ok := k.labels[layout]; ok {
+		return label
+	}
+
+	return providers.LayoutCode(layout, k.codes)
+}
+
+func (k *Keyboard) Name() string {
+	return "keyboard"
+}
+
+func (k *Keyboard) Box() *gtk.Box {
+	return k.box
+}
@@ -0,0 +1,46 @@
+package widgets
+
+import (
+	"context"
+	"sync"
+)
+
+// Lifecycle tracks the goroutines a widget runs between Start and Stop.
+// Widgets embed it, launch their background work with Go from Start and get
+// a Stop method that cancels that work and waits for it to return.
+type Lifecycle struct {
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Go runs fn in a new goroutine. The context passed to fn is cancelled when
+// ctx is done or Stop is called.
+func (l *Lifecycle) Go(ctx context.Context, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	l.mu.Lock()
+	l.cancels = append(l.cancels, cancel)
+	l.wg.Add(1)
+	l.mu.Unlock()
+
+	go func() {
+		defer l.wg.Done()
+		fn(ctx)
+	}()
+}
+
+// Stop cancels every goroutine started with Go and waits for them to exit.
+// The widget can be started again afterwards.
+func (l *Lifecycle) Stop() {
+	l.mu.Lock()
+	cancels := l.cancels
+	l.cancels = nil
+	l.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	l.wg.Wait()
+}
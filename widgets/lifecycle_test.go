@@ -0,0 +1,110 @@
+package widgets
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitGoroutines fails the test unless the number of goroutines drops back
+// to baseline within a few seconds.
+func waitGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Fatalf("%d goroutines running, want %d:\n%s", n, baseline, buf)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// goBlocked starts n goroutines on l that run until their context is done,
+// and waits until they all started.
+func goBlocked(ctx context.Context, l *Lifecycle, n int, exited *atomic.Int32) {
+	started := make(chan struct{}, n)
+	for range n {
+		l.Go(ctx, func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			exited.Add(1)
+		})
+	}
+
+	for range n {
+		<-started
+	}
+}
+
+func TestLifecycleStop(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	var l Lifecycle
+	var exited atomic.Int32
+	goBlocked(context.Background(), &l, 3, &exited)
+
+	// Stop waits for the goroutines instead of only cancelling them
+	l.Stop()
+	if n := exited.Load(); n != 3 {
+		t.Errorf("%d goroutines exited when Stop returned, want 3", n)
+	}
+	waitGoroutines(t, baseline)
+
+	// Stopping again is harmless, and the widget can be started again
+	l.Stop()
+	goBlocked(context.Background(), &l, 2, &exited)
+	l.Stop()
+	if n := exited.Load(); n != 5 {
+		t.Errorf("%d goroutines exited after the restart, want 5", n)
+	}
+	waitGoroutines(t, baseline)
+}
+
+func TestLifecycleContext(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	var l Lifecycle
+	var exited atomic.Int32
+
+	// Goroutines also end with the context passed to Start
+	ctx, cancel := context.WithCancel(context.Background())
+	goBlocked(ctx, &l, 2, &exited)
+	cancel()
+	waitGoroutines(t, baseline)
+
+	// Stop then has nothing left to wait for
+	done := make(chan struct{})
+	go func() {
+		l.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+}
+
+func TestLifecycleFinishedWork(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	var l Lifecycle
+	ran := make(chan struct{})
+	l.Go(context.Background(), func(ctx context.Context) {
+		close(ran)
+	})
+	<-ran
+
+	l.Stop()
+	waitGoroutines(t, baseline)
+}
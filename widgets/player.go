@@ -1,28 +1,41 @@
 package widgets
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
 	"time"
 
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/providers"
 )
 
 type Player struct {
-	box       *gtk.Box
-	label     *gtk.Label
-	status    string
-	title     string
-	artist    string
-	position  time.Duration
-	duration  time.Duration
-	isPlaying bool
+	Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Player
+	interval time.Duration
 }
 
-func NewPlayer() *Player {
-	return &Player{}
+func init() {
+	Register("player", func(opts *config.Options) (Widget, error) {
+		interval, err := opts.Duration("interval", 1*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlayer(interval), nil
+	})
+}
+
+func NewPlayer(interval time.Duration) *Player {
+	if interval == 0 {
+		interval = 1 * time.Second
+	}
+	return &Player{
+		provider: providers.NewPlayer(),
+		interval: interval,
+	}
 }
 
 func (p *Player) Create() error {
@@ -41,78 +54,52 @@ func (p *Player) Create() error {
 	p.label = label
 	p.box.PackStart(label, true, true, 0)
 
-	// Start listening for player events
-	go p.listenForPlayerEvents()
-
 	return nil
 }
 
-func (p *Player) listenForPlayerEvents() {
-	for {
-		cmd := exec.Command("playerctl", "status", "--format", "{{status}}")
-		status, err := cmd.Output()
-		if err != nil {
-			p.status = "Stopped"
-			p.isPlaying = false
-			glib.IdleAdd(p.updateLabel)
-			time.Sleep(1 * time.Second)
-			continue
-		}
-
-		p.status = strings.TrimSpace(string(status))
-		p.isPlaying = p.status == "Playing"
-
-		// Get metadata
-		cmd = exec.Command("playerctl", "metadata", "--format", "{{title}}|{{artist}}")
-		metadata, err := cmd.Output()
-		if err == nil {
-			parts := strings.Split(strings.TrimSpace(string(metadata)), "|")
-			if len(parts) >= 2 {
-				p.title = parts[0]
-				p.artist = parts[1]
-			}
-		}
+func (p *Player) Start(ctx context.Context) error {
+	return nil // Player state is collected by Poll
+}
 
-		// Get position and duration
-		cmd = exec.Command("playerctl", "position")
-		pos, err := cmd.Output()
-		if err == nil {
-			if pos, err := time.ParseDuration(strings.TrimSpace(string(pos)) + "s"); err == nil {
-				p.position = pos
-			}
-		}
+func (p *Player) Destroy() {
+	p.Stop()
+	p.box.Destroy()
+}
 
-		cmd = exec.Command("playerctl", "metadata", "mpris:length")
-		dur, err := cmd.Output()
-		if err == nil {
-			if dur, err := time.ParseDuration(strings.TrimSpace(string(dur)) + "ns"); err == nil {
-				p.duration = dur
-			}
-		}
+func (p *Player) Interval() time.Duration {
+	return p.interval
+}
 
-		glib.IdleAdd(p.updateLabel)
-		time.Sleep(1 * time.Second)
+// Poll queries the player and returns a function showing the current track.
+func (p *Player) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := p.provider.Poll(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	return func() {
+		p.render(snapshot)
+	}, nil
 }
 
-func (p *Player) updateLabel() {
-	if !p.isPlaying {
+func (p *Player) render(snapshot providers.PlayerSnapshot) {
+	if !snapshot.IsPlaying() {
 		p.label.SetText("No media playing")
 		return
 	}
 
 	// Format time
-	pos := formatDuration(p.position)
-	dur := formatDuration(p.duration)
+	pos := formatDuration(snapshot.Position)
+	dur := formatDuration(snapshot.Duration)
 
 	// Truncate long titles
-	title := p.title
+	title := snapshot.Title
 	if len(title) > 40 {
 		title = title[:37] + "..."
 	}
 
 	// Truncate long artist names
-	artist := p.artist
+	artist := snapshot.Artist
 	if len(artist) > 30 {
 		artist = artist[:27] + "..."
 	}
@@ -131,10 +118,6 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%02d:%02d", minutes, seconds)
 }
 
-func (p *Player) Render() error {
-	return nil
-}
-
 func (p *Player) Name() string {
 	return "player"
 }
@@ -0,0 +1,197 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/providers"
+)
+
+// Submap shows the active Hyprland submap and hides itself in the default
+// one, as it does under other compositors. The box gets a submap-NAME CSS
+// class for the active submap, and with the tooltip option its key
+// bindings are listed in the tooltip.
+type Submap struct {
+	Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Submap
+	tooltip  bool
+
+	// Accessed on the GTK thread only
+	ctx       context.Context
+	hyprland  bool
+	current   string
+	class     string
+	destroyed bool
+}
+
+func init() {
+	Register("submap", func(opts *config.Options) (Widget, error) {
+		tooltip, err := opts.Bool("tooltip", false)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSubmap(tooltip), nil
+	})
+}
+
+func NewSubmap(tooltip bool) *Submap {
+	return &Submap{
+		provider: providers.NewSubmap(),
+		tooltip:  tooltip,
+	}
+}
+
+func (s *Submap) Create() error {
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		return fmt.Errorf("unable to create box: %w", err)
+	}
+
+	// The box is shown only while a submap is active, whatever the bar
+	// shows around it
+	box.SetNoShowAll(true)
+	s.box = box
+
+	label, err := gtk.LabelNew("")
+	if err != nil {
+		return fmt.Errorf("unable to create label: %w", err)
+	}
+
+	box.PackStart(label, true, true, 0)
+	label.Show()
+
+	// A widget created again after Destroy starts from the default submap
+	s.label = label
+	s.current = ""
+	s.class = ""
+	return nil
+}
+
+func (s *Submap) SetCompositor(kind string) {
+	s.hyprland = kind == compositor.KindHyprland
+}
+
+func (s *Submap) Start(ctx context.Context) error {
+	s.ctx = ctx
+	s.destroyed = false
+
+	// Submaps are a Hyprland feature, so the widget stays hidden otherwise
+	if s.hyprland {
+		s.Resync()
+	}
+
+	return nil
+}
+
+func (s *Submap) Destroy() {
+	s.destroyed = true
+	s.Stop()
+	s.box.Destroy()
+}
+
+func (s *Submap) Events() []string {
+	return []string{"submap"}
+}
+
+func (s *Submap) HandleEvent(ev libs.Event) {
+	decoded, err := ev.Decode()
+	if err != nil {
+		libs.Log.Println("unable to decode submap event:", err)
+		return
+	}
+
+	if submap, ok := decoded.(libs.SubmapEvent); ok {
+		s.render(submap.Name)
+	}
+}
+
+// Resync asks Hyprland for the active submap, as submap events may have
+// been missed.
+func (s *Submap) Resync() {
+	s.Go(s.ctx, func(ctx context.Context) {
+		name, err := s.provider.Current(ctx)
+		if err != nil {
+			libs.Log.Println("unable to query submap:", err)
+			return
+		}
+
+		glib.IdleAdd(func() {
+			if !s.destroyed {
+				s.render(name)
+			}
+		})
+	})
+}
+
+// render shows the named submap, or hides the widget for the default one.
+func (s *Submap) render(name string) {
+	if name == s.current {
+		return
+	}
+	s.current = name
+
+	styleContext, err := s.box.GetStyleContext()
+	if err == nil {
+		if s.class != "" {
+			styleContext.RemoveClass(s.class)
+		}
+		s.class = ""
+		if name != "" {
+			s.class = providers.SubmapClass(name)
+			styleContext.AddClass(s.class)
+		}
+	}
+
+	s.label.SetText(name)
+
+	if name == "" {
+		s.box.Hide()
+		return
+	}
+
+	s.box.Show()
+
+	if s.tooltip {
+		s.box.SetTooltipText("")
+		s.loadBindings(name)
+	}
+}
+
+// loadBindings lists the key bindings of the named submap in the tooltip.
+func (s *Submap) loadBindings(name string) {
+	s.Go(s.ctx, func(ctx context.Context) {
+		bindings, err := s.provider.Bindings(ctx, name)
+		if err != nil {
+			libs.Log.Println("unable to query submap bindings:", err)
+			return
+		}
+
+		lines := make([]string, 0, len(bindings))
+		for _, b := range bindings {
+			lines = append(lines, b.Keys+"\t"+b.Action)
+		}
+
+		glib.IdleAdd(func() {
+			if !s.destroyed && s.current == name {
+				s.box.SetTooltipText(strings.Join(lines, "\n"))
+			}
+		})
+	})
+}
+
+func (s *Submap) Name() string {
+	return "submap"
+}
+
+func (s *Submap) Box() *gtk.Box {
+	return s.box
+}
@@ -1,37 +1,42 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/providers"
+	"github.com/grentenrg/go-bar/widgets"
 )
 
 type CPU struct {
-	box    *gtk.Box
-	label  *gtk.Label
-	prev   cpuTimes
-	usage  float64
-	ticker *time.Ticker
+	widgets.Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.CPU
+	interval time.Duration
 }
 
-type cpuTimes struct {
-	user    uint64
-	nice    uint64
-	system  uint64
-	idle    uint64
-	iowait  uint64
-	irq     uint64
-	softirq uint64
-	steal   uint64
+func init() {
+	widgets.Register("cpu", func(opts *config.Options) (widgets.Widget, error) {
+		interval, err := opts.Duration("interval", 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewCPU(interval), nil
+	})
 }
 
-func NewCPU() *CPU {
-	return &CPU{}
+func NewCPU(interval time.Duration) *CPU {
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	return &CPU{
+		provider: providers.NewCPU(),
+		interval: interval,
+	}
 }
 
 func (c *CPU) Create() error {
@@ -50,43 +55,36 @@ func (c *CPU) Create() error {
 	box.PackStart(label, false, false, 0)
 	c.label = label
 
-	// Start monitoring CPU usage
-	c.ticker = time.NewTicker(2 * time.Second)
-	go c.monitor()
-
 	return nil
 }
 
-func (c *CPU) monitor() {
-	for range c.ticker.C {
-		if err := c.updateUsage(); err != nil {
-			fmt.Printf("Error updating CPU usage: %v\n", err)
-			continue
-		}
+func (c *CPU) Start(ctx context.Context) error {
+	return nil // Updates are collected by Poll
+}
 
-		glib.IdleAdd(func() {
-			c.label.SetLabel(fmt.Sprintf("💻 %.1f%%", c.usage))
-		})
-	}
+func (c *CPU) Destroy() {
+	c.Stop()
+	c.box.Destroy()
 }
 
-func (c *CPU) updateUsage() error {
-	cmd := exec.Command("sh", "-c", "top -bn1 | grep 'Cpu(s)' | awk '{print $2}'")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("unable to get CPU usage: %w", err)
-	}
+func (c *CPU) Interval() time.Duration {
+	return c.interval
+}
 
-	usage, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+// Poll reads the CPU usage and returns a function showing it.
+func (c *CPU) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := c.provider.Poll(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to parse CPU usage: %w", err)
+		return nil, err
 	}
 
-	glib.IdleAdd(func() {
-		c.label.SetLabel(fmt.Sprintf("💻 %.1f%%", usage))
-	})
+	return func() {
+		c.render(snapshot)
+	}, nil
+}
 
-	return nil
+func (c *CPU) render(snapshot providers.CPUSnapshot) {
+	c.label.SetLabel(fmt.Sprintf("💻 %.1f%%", snapshot.Usage))
 }
 
 func (c *CPU) Name() string {
@@ -96,7 +94,3 @@ func (c *CPU) Name() string {
 func (c *CPU) Box() *gtk.Box {
 	return c.box
 }
-
-func (c *CPU) Render() error {
-	return nil // Updates handled by monitor goroutine
-}
@@ -1,26 +1,45 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/providers"
+	"github.com/grentenrg/go-bar/widgets"
 )
 
 type Disk struct {
-	box    *gtk.Box
-	label  *gtk.Label
-	path   string
-	ticker *time.Ticker
+	widgets.Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Disk
+	interval time.Duration
 }
 
-func NewDisk(path string) *Disk {
+func init() {
+	widgets.Register("disk", func(opts *config.Options) (widgets.Widget, error) {
+		path, err := opts.String("path", "/")
+		if err != nil {
+			return nil, err
+		}
+		interval, err := opts.Duration("interval", 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewDisk(path, interval), nil
+	})
+}
+
+func NewDisk(path string, interval time.Duration) *Disk {
+	if interval == 0 {
+		interval = 30 * time.Second // Less frequent updates for disk
+	}
 	return &Disk{
-		path: path,
+		provider: providers.NewDisk(path),
+		interval: interval,
 	}
 }
 
@@ -40,39 +59,36 @@ func (d *Disk) Create() error {
 	box.PackStart(label, false, false, 0)
 	d.label = label
 
-	// Start monitoring disk usage
-	d.ticker = time.NewTicker(30 * time.Second) // Less frequent updates for disk
-	go d.monitor()
-
 	return nil
 }
 
-func (d *Disk) monitor() {
-	for range d.ticker.C {
-		if err := d.updateUsage(); err != nil {
-			fmt.Printf("Error updating disk usage: %v\n", err)
-			continue
-		}
-	}
+func (d *Disk) Start(ctx context.Context) error {
+	return nil // Updates are collected by Poll
 }
 
-func (d *Disk) updateUsage() error {
-	cmd := exec.Command("sh", "-c", "df -h "+d.path+" | tail -1 | awk '{print $5}' | sed 's/%//'")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("unable to get disk usage: %w", err)
-	}
+func (d *Disk) Destroy() {
+	d.Stop()
+	d.box.Destroy()
+}
 
-	usage, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+func (d *Disk) Interval() time.Duration {
+	return d.interval
+}
+
+// Poll reads the disk usage and returns a function showing it.
+func (d *Disk) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := d.provider.Poll(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to parse disk usage: %w", err)
+		return nil, err
 	}
 
-	glib.IdleAdd(func() {
-		d.label.SetLabel(fmt.Sprintf("💾 %.1f%%", usage))
-	})
+	return func() {
+		d.render(snapshot)
+	}, nil
+}
 
-	return nil
+func (d *Disk) render(snapshot providers.DiskSnapshot) {
+	d.label.SetLabel(fmt.Sprintf("💾 %.1f%%", snapshot.Usage))
 }
 
 func (d *Disk) Name() string {
@@ -82,7 +98,3 @@ func (d *Disk) Name() string {
 func (d *Disk) Box() *gtk.Box {
 	return d.box
 }
-
-func (d *Disk) Render() error {
-	return nil // Updates handled by monitor goroutine
-}
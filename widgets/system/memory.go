@@ -1,24 +1,42 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/providers"
+	"github.com/grentenrg/go-bar/widgets"
 )
 
 type Memory struct {
-	box    *gtk.Box
-	label  *gtk.Label
-	ticker *time.Ticker
+	widgets.Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Memory
+	interval time.Duration
 }
 
-func NewMemory() *Memory {
-	return &Memory{}
+func init() {
+	widgets.Register("memory", func(opts *config.Options) (widgets.Widget, error) {
+		interval, err := opts.Duration("interval", 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemory(interval), nil
+	})
+}
+
+func NewMemory(interval time.Duration) *Memory {
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	return &Memory{
+		provider: providers.NewMemory(),
+		interval: interval,
+	}
 }
 
 func (m *Memory) Create() error {
@@ -29,7 +47,7 @@ func (m *Memory) Create() error {
 
 	m.box = box
 
-	label, err := gtk.LabelNew("MEM: ---%")
+	label, err := gtk.LabelNew("Memory: ---%")
 	if err != nil {
 		return fmt.Errorf("unable to create label: %w", err)
 	}
@@ -37,39 +55,36 @@ func (m *Memory) Create() error {
 	box.PackStart(label, false, false, 0)
 	m.label = label
 
-	// Start monitoring memory usage
-	m.ticker = time.NewTicker(2 * time.Second)
-	go m.monitor()
-
 	return nil
 }
 
-func (m *Memory) monitor() {
-	for range m.ticker.C {
-		if err := m.updateUsage(); err != nil {
-			fmt.Printf("Error updating memory usage: %v\n", err)
-			continue
-		}
-	}
+func (m *Memory) Start(ctx context.Context) error {
+	return nil // Updates are collected by Poll
 }
 
-func (m *Memory) updateUsage() error {
-	cmd := exec.Command("sh", "-c", "free | grep Mem | awk '{print $3/$2 * 100.0}'")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("unable to get memory usage: %w", err)
-	}
+func (m *Memory) Destroy() {
+	m.Stop()
+	m.box.Destroy()
+}
 
-	usage, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+func (m *Memory) Interval() time.Duration {
+	return m.interval
+}
+
+// Poll reads the Memory usage and returns a function showing it.
+func (m *Memory) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := m.provider.Poll(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to parse memory usage: %w", err)
+		return nil, err
 	}
 
-	glib.IdleAdd(func() {
-		m.label.SetLabel(fmt.Sprintf("🧠 %.1f%%", usage))
-	})
+	return func() {
+		m.render(snapshot)
+	}, nil
+}
 
-	return nil
+func (m *Memory) render(snapshot providers.MemorySnapshot) {
+	m.label.SetLabel(fmt.Sprintf("🧠 %.1f%%", snapshot.Usage))
 }
 
 func (m *Memory) Name() string {
@@ -79,7 +94,3 @@ func (m *Memory) Name() string {
 func (m *Memory) Box() *gtk.Box {
 	return m.box
 }
-
-func (m *Memory) Render() error {
-	return nil // Updates handled by monitor goroutine
-}
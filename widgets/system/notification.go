@@ -1,22 +1,44 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
+	"time"
 
 	"github.com/gotk3/gotk3/gdk"
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/providers"
+	"github.com/grentenrg/go-bar/widgets"
 )
 
 type Notification struct {
-	box   *gtk.Box
-	label *gtk.Label
+	widgets.Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Notification
+	interval time.Duration
 }
 
-func NewNotification() *Notification {
-	return &Notification{}
+func init() {
+	widgets.Register("notification", func(opts *config.Options) (widgets.Widget, error) {
+		interval, err := opts.Duration("interval", 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewNotification(interval), nil
+	})
+}
+
+func NewNotification(interval time.Duration) *Notification {
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	return &Notification{
+		provider: providers.NewNotification(),
+		interval: interval,
+	}
 }
 
 func (n *Notification) Create() error {
@@ -44,29 +66,39 @@ func (n *Notification) Create() error {
 	box.PackStart(eventBox, false, false, 0)
 	n.label = label
 
-	// Start monitoring notifications
-	go n.monitor()
-
 	return nil
 }
 
-func (n *Notification) monitor() {
-	cmd := exec.Command("swaync-client", "-c")
-	output, err := cmd.Output()
+func (n *Notification) Start(ctx context.Context) error {
+	return nil // Updates are collected by Poll
+}
+
+func (n *Notification) Destroy() {
+	n.Stop()
+	n.box.Destroy()
+}
+
+func (n *Notification) Interval() time.Duration {
+	return n.interval
+}
+
+// Poll reads the number of notifications and returns a function showing it.
+func (n *Notification) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := n.provider.Poll(ctx)
 	if err != nil {
-		fmt.Printf("Error getting notification count: %v\n", err)
-		return
+		return nil, err
 	}
 
-	count := strings.TrimSpace(string(output))
-	if count != "0" {
-		glib.IdleAdd(func() {
-			n.label.SetLabel(fmt.Sprintf("🔔 %s", count))
-		})
+	return func() {
+		n.render(snapshot)
+	}, nil
+}
+
+func (n *Notification) render(snapshot providers.NotificationSnapshot) {
+	if snapshot.Count != "0" {
+		n.label.SetLabel(fmt.Sprintf("🔔 %s", snapshot.Count))
 	} else {
-		glib.IdleAdd(func() {
-			n.label.SetLabel("🔔")
-		})
+		n.label.SetLabel("🔔")
 	}
 }
 
@@ -74,9 +106,8 @@ func (n *Notification) handleClick(event *gtk.EventBox, eventBtn *gdk.Event) boo
 	buttonEvent := gdk.EventButtonNewFromEvent(eventBtn)
 	if buttonEvent.Button() == 1 { // Left click
 		// Toggle notification center
-		cmd := exec.Command("swaync-client", "-t")
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error toggling notification center: %v\n", err)
+		if err := n.provider.Toggle(context.Background()); err != nil {
+			libs.Log.Println("unable to toggle notification center:", err)
 		}
 	}
 	return true
@@ -89,7 +120,3 @@ func (n *Notification) Name() string {
 func (n *Notification) Box() *gtk.Box {
 	return n.box
 }
-
-func (n *Notification) Render() error {
-	return nil // Updates handled by monitor goroutine
-}
@@ -1,30 +1,47 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gotk3/gotk3/gdk"
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/providers"
+	"github.com/grentenrg/go-bar/widgets"
 )
 
 type Volume struct {
-	box    *gtk.Box
-	label  *gtk.Label
-	sink   string
-	ticker *time.Ticker
+	widgets.Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Volume
+	interval time.Duration
 }
 
-func NewVolume(sink string) *Volume {
-	if sink == "" {
-		sink = "@DEFAULT_SINK@"
+func init() {
+	widgets.Register("volume", func(opts *config.Options) (widgets.Widget, error) {
+		sink, err := opts.String("sink", "")
+		if err != nil {
+			return nil, err
+		}
+		interval, err := opts.Duration("interval", 1*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewVolume(sink, interval), nil
+	})
+}
+
+func NewVolume(sink string, interval time.Duration) *Volume {
+	if interval == 0 {
+		interval = 1 * time.Second
 	}
 	return &Volume{
-		sink: sink,
+		provider: providers.NewVolume(sink),
+		interval: interval,
 	}
 }
 
@@ -57,79 +74,53 @@ func (v *Volume) Create() error {
 	box.PackStart(eventBox, false, false, 0)
 	v.label = label
 
-	// Start monitoring volume
-	v.ticker = time.NewTicker(1 * time.Second)
-	go v.monitor()
-
 	return nil
 }
 
-func (v *Volume) monitor() {
-	for range v.ticker.C {
-		if err := v.updateUsage(); err != nil {
-			fmt.Printf("Error updating volume: %v\n", err)
-			continue
-		}
-	}
+func (v *Volume) Start(ctx context.Context) error {
+	return nil // Updates are collected by Poll
+}
+
+func (v *Volume) Destroy() {
+	v.Stop()
+	v.box.Destroy()
 }
 
-func (v *Volume) updateUsage() error {
-	cmd := exec.Command("pactl", "get-sink-volume", v.sink)
-	output, err := cmd.Output()
+func (v *Volume) Interval() time.Duration {
+	return v.interval
+}
+
+// Poll reads the sink volume and mute state and returns a function showing
+// them.
+func (v *Volume) Poll(ctx context.Context) (func(), error) {
+	snapshot, err := v.provider.Poll(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to get volume: %w", err)
+		return nil, err
 	}
 
-	// Parse volume percentage
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "Volume:") {
-			fields := strings.Fields(line)
-			if len(fields) < 5 {
-				continue
-			}
-
-			// Extract percentage value
-			percent := strings.TrimSuffix(fields[4], "%")
-			volume, err := strconv.Atoi(percent)
-			if err != nil {
-				return fmt.Errorf("unable to parse volume: %w", err)
-			}
-
-			// Check if muted
-			cmd = exec.Command("pactl", "get-sink-mute", v.sink)
-			output, err = cmd.Output()
-			if err != nil {
-				return fmt.Errorf("unable to get mute status: %w", err)
-			}
-
-			muted := strings.Contains(string(output), "yes")
-
-			glib.IdleAdd(func() {
-				icon := "🔊"
-				if muted {
-					icon = "🔇"
-				} else if volume == 0 {
-					icon = "🔈"
-				} else if volume < 50 {
-					icon = "🔉"
-				}
-				v.label.SetLabel(fmt.Sprintf("%s %d%%", icon, volume))
-			})
-			break
-		}
-	}
+	return func() {
+		v.render(snapshot)
+	}, nil
+}
 
-	return nil
+func (v *Volume) render(snapshot providers.VolumeSnapshot) {
+	icon := "🔊"
+	if snapshot.Muted {
+		icon = "🔇"
+	} else if snapshot.Volume == 0 {
+		icon = "🔈"
+	} else if snapshot.Volume < 50 {
+		icon = "🔉"
+	}
+	v.label.SetLabel(fmt.Sprintf("%s %d%%", icon, snapshot.Volume))
 }
 
 func (v *Volume) handleClick(event *gtk.EventBox, eventBtn *gdk.Event) bool {
 	buttonEvent := gdk.EventButtonNewFromEvent(eventBtn)
 	if buttonEvent.Button() == 1 { // Left click
 		// Toggle mute
-		cmd := exec.Command("pactl", "set-sink-mute", v.sink, "toggle")
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error toggling mute: %v\n", err)
+		if err := v.provider.ToggleMute(context.Background()); err != nil {
+			libs.Log.Println("unable to toggle mute:", err)
 		}
 	}
 	return true
@@ -141,15 +132,13 @@ func (v *Volume) handleScroll(event *gtk.EventBox, scrollEvent *gdk.Event) bool
 
 	if direction == gdk.SCROLL_UP || direction == gdk.SCROLL_SMOOTH && scroll.DeltaY() < 0 {
 		// Increase volume by 5%
-		cmd := exec.Command("pactl", "set-sink-volume", v.sink, "+5%")
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error increasing volume: %v\n", err)
+		if err := v.provider.Adjust(context.Background(), 5); err != nil {
+			libs.Log.Println("unable to increase volume:", err)
 		}
 	} else if direction == gdk.SCROLL_DOWN || direction == gdk.SCROLL_SMOOTH && scroll.DeltaY() > 0 {
 		// Decrease volume by 5%
-		cmd := exec.Command("pactl", "set-sink-volume", v.sink, "-5%")
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error decreasing volume: %v\n", err)
+		if err := v.provider.Adjust(context.Background(), -5); err != nil {
+			libs.Log.Println("unable to decrease volume:", err)
 		}
 	}
 	return true
@@ -162,7 +151,3 @@ func (v *Volume) Name() string {
 func (v *Volume) Box() *gtk.Box {
 	return v.box
 }
-
-func (v *Volume) Render() error {
-	return nil // Updates handled by monitor goroutine
-}
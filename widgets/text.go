@@ -0,0 +1,80 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/config"
+)
+
+// Text shows a fixed text that scripts can change at runtime with
+// "go-bar msg set-text".
+type Text struct {
+	Lifecycle
+	box   *gtk.Box
+	label *gtk.Label
+	text  string
+}
+
+func init() {
+	Register("text", func(opts *config.Options) (Widget, error) {
+		text, err := opts.String("text", "")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewText(text), nil
+	})
+}
+
+func NewText(text string) *Text {
+	return &Text{text: text}
+}
+
+func (t *Text) Create() error {
+	box, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	if err != nil {
+		return fmt.Errorf("unable to create box: %w", err)
+	}
+
+	t.box = box
+
+	label, err := gtk.LabelNew(t.text)
+	if err != nil {
+		return fmt.Errorf("unable to create label: %w", err)
+	}
+
+	box.PackStart(label, true, true, 0)
+
+	t.label = label
+	return nil
+}
+
+func (t *Text) Start(ctx context.Context) error {
+	return nil
+}
+
+func (t *Text) Destroy() {
+	t.Stop()
+	t.box.Destroy()
+}
+
+func (t *Text) Text() string {
+	return t.text
+}
+
+func (t *Text) SetText(text string) {
+	t.text = text
+	if t.label != nil {
+		t.label.SetText(text)
+	}
+}
+
+func (t *Text) Name() string {
+	return "text"
+}
+
+func (t *Text) Box() *gtk.Box {
+	return t.box
+}
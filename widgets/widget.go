@@ -0,0 +1,161 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/libs"
+)
+
+type Widget interface {
+	// Create builds the GTK widgets, on the GTK thread. It is called again
+	// when activation is retried; after a failure, the bar destroys the box
+	// returned by Box, if any.
+	Create() error
+	// Start launches the widget's background work, which runs until ctx is
+	// done or Stop is called.
+	Start(ctx context.Context) error
+	// Stop ends the work launched by Start and waits for it to finish.
+	Stop()
+	// Destroy stops the widget and releases its GTK widgets.
+	Destroy()
+	Name() string
+	Box() *gtk.Box
+}
+
+// Poller is implemented by widgets that refresh periodically. The scheduler
+// calls Poll every Interval away from the GTK thread and runs the returned
+// function, if any, on the GTK thread.
+type Poller interface {
+	Interval() time.Duration
+	Poll(ctx context.Context) (func(), error)
+}
+
+// EventHandler is implemented by widgets that follow Hyprland events. Once
+// the widget is running under Hyprland, the bar subscribes it to the events
+// named by Events on the shared event bus, and unsubscribes it before
+// destroying it.
+// HandleEvent is called on the GTK thread, as is Resync when the bus
+// reconnected and events may have been missed.
+type EventHandler interface {
+	Events() []string
+	HandleEvent(ev libs.Event)
+	Resync()
+}
+
+// CompositorSetter is implemented by widgets that only work under some
+// compositors. The bar calls SetCompositor on the GTK thread before Start,
+// with the kind of the detected compositor, one of the compositor.Kind
+// constants.
+type CompositorSetter interface {
+	SetCompositor(kind string)
+}
+
+// CompositorWatcher is implemented by widgets that render the state of the
+// compositor. Once the widget is running, the bar registers it with the
+// compositor backend, and unregisters it before destroying it.
+// CompositorChanged is called on the GTK thread whenever the state changed,
+// with the name of the output showing the bar, or "" while it is unknown.
+type CompositorWatcher interface {
+	CompositorChanged(c compositor.Compositor, output string)
+}
+
+// TextSetter is implemented by widgets whose text can be set from outside
+// the bar. Both methods are called on the GTK thread.
+type TextSetter interface {
+	Text() string
+	SetText(text string)
+}
+
+// Factory builds a widget from the options of its configuration entry.
+type Factory func(opts *config.Options) (Widget, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a widget type available to the configuration under name.
+// It is meant to be called from init functions and panics if the same name
+// is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("widgets: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("widgets: Register called twice for " + name)
+	}
+
+	registry[name] = factory
+}
+
+// Types returns the names of all registered widget types, sorted.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Describe returns the options supported by a widget type together with
+// their defaults.
+func Describe(name string) ([]config.Option, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown widget type %q", name)
+	}
+
+	// Factories only look up options, so building a throwaway widget from
+	// empty options reveals all of them
+	opts := config.NewOptions()
+	if _, err := factory(opts); err != nil {
+		return nil, err
+	}
+
+	return opts.Known(), nil
+}
+
+// New builds the widget described by a configuration entry.
+func New(wc config.WidgetConfig) (Widget, error) {
+	registryMu.RLock()
+	factory, ok := registry[wc.Type]
+	registryMu.RUnlock()
+
+	opts := wc.Options
+	if opts == nil {
+		opts = config.NewOptions()
+	}
+
+	if !ok {
+		return nil, opts.Errorf("type", "unknown widget type %q", wc.Type)
+	}
+
+	widget, err := factory(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := opts.CheckUnused(); err != nil {
+		return nil, err
+	}
+
+	return widget, nil
+}
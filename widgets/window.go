@@ -1,23 +1,33 @@
 package widgets
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/gotk3/gotk3/pango"
-	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
+	"github.com/grentenrg/go-bar/providers"
 )
 
 type Window struct {
-	box           *gtk.Box
-	label         *gtk.Label
-	currentWindow string
-	changed       bool
+	Lifecycle
+	box      *gtk.Box
+	label    *gtk.Label
+	provider *providers.Window
+}
+
+func init() {
+	Register("window", func(opts *config.Options) (Widget, error) {
+		return NewWindow(), nil
+	})
 }
 
 func NewWindow() *Window {
-	return &Window{}
+	return &Window{
+		provider: providers.NewWindow(),
+	}
 }
 
 func (w *Window) Create() error {
@@ -44,33 +54,24 @@ func (w *Window) Create() error {
 
 	w.label = elem
 
-	libs.ListenForHyprlandEvents(w.Update)
-
 	return nil
 }
 
-func (w *Window) Update(eventType, data string) {
-	switch eventType {
-	case "activewindow":
-		w.currentWindow = data
-		w.changed = true
-		glib.IdleAdd(func() {
-			if err := w.Render(); err != nil {
-				fmt.Println("Unable to render window:", err)
-			}
-		})
-	}
+func (w *Window) Start(ctx context.Context) error {
+	return nil
 }
 
-func (w *Window) Render() error {
-	if !w.changed {
-		return nil
-	}
+func (w *Window) Destroy() {
+	w.Stop()
+	w.box.Destroy()
+}
 
-	w.label.SetLabel(w.currentWindow)
-	w.changed = false
+func (w *Window) CompositorChanged(c compositor.Compositor, output string) {
+	w.render(w.provider.Snapshot(c.State()))
+}
 
-	return nil
+func (w *Window) render(snapshot providers.WindowSnapshot) {
+	w.label.SetLabel(snapshot.Title)
 }
 
 func (w *Window) Name() string {
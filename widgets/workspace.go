@@ -1,45 +1,120 @@
 package widgets
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
+	"slices"
 	"strings"
 
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/grentenrg/go-bar/compositor"
+	"github.com/grentenrg/go-bar/config"
 	"github.com/grentenrg/go-bar/libs"
+	"github.com/grentenrg/go-bar/providers"
 )
 
-type hyprlandWorkspace struct {
-	ID                   int    `json:"id"`
-	Name                 string `json:"name"`
-	LastActiveWindowName string `json:"lastwindowtitle"`
-	Monitor              string `json:"monitor"`
-	IsActive             bool   `json:"active"`
+// workspaceOutputBar is the value of the output option selecting the output
+// showing the bar.
+const workspaceOutputBar = "bar"
+
+type Workspace struct {
+	Lifecycle
+	box      *gtk.Box
+	buttons  map[workspaceKey]*workspaceButton
+	provider *providers.Workspaces
+	// output selects the workspaces shown: "" for all of them, "bar" for
+	// those of the output showing the bar, or the name or ID of an output.
+	output string
+	// icons enables application icons on the buttons, at most maxIcons per
+	// button followed by "+N" for the others.
+	icons    bool
+	iconMap  map[string]string
+	maxIcons int
+	// persistent lists the workspaces shown even when they do not exist
+	persistent []string
+	// urgentTooltip lists the titles of urgent windows in the tooltip of
+	// their workspace
+	urgentTooltip bool
+	// compositor is the backend the workspaces were last rendered from
+	compositor compositor.Compositor
+
+	// Accessed on the GTK thread only
+	ctx       context.Context
+	snapshot  providers.WorkspacesSnapshot // last rendered
+	destroyed bool
 }
 
-type monitors struct {
-	Name              string `json:"name"`
-	Active            bool   `json:"active"`
-	ID                int    `json:"id"`
-	ActiveWorkspaceID int    `json:"activeWorkspace.id"`
+// workspaceKey identifies a workspace button. Names are only unique per
+// output on some compositors.
+type workspaceKey struct {
+	output string
+	name   string
 }
 
-type Workspace struct {
-	box             *gtk.Box
-	workspaces      []hyprlandWorkspace
-	buttons         map[string]*gtk.Button
-	activeWorkspace string
-	activeMonitor   string // Current monitor name
-	monitors        []monitors
+// workspaceButton is the button of a workspace.
+type workspaceButton struct {
+	button  *gtk.Button
+	content *gtk.Box
+	apps    []string // apps shown, to rebuild the content only on change
+	filled  bool
+}
+
+func init() {
+	Register("workspace", func(opts *config.Options) (Widget, error) {
+		output, err := opts.String("output", "")
+		if err != nil {
+			return nil, err
+		}
+
+		icons, err := opts.Bool("icons", false)
+		if err != nil {
+			return nil, err
+		}
+
+		iconMap, err := opts.StringMap("icon-map")
+		if err != nil {
+			return nil, err
+		}
+
+		maxIcons, err := opts.Int("max-icons", 4)
+		if err != nil {
+			return nil, err
+		}
+		if maxIcons < 1 {
+			return nil, opts.Errorf("max-icons", "expected at least 1, got %d", maxIcons)
+		}
+
+		spec, err := opts.String("persistent", "")
+		if err != nil {
+			return nil, err
+		}
+		persistent, err := providers.ParseWorkspaceList(spec)
+		if err != nil {
+			return nil, opts.Errorf("persistent", "%v", err)
+		}
+
+		urgentTooltip, err := opts.Bool("urgent-tooltip", false)
+		if err != nil {
+			return nil, err
+		}
+
+		w := NewWorkspace(output)
+		w.persistent = persistent
+		w.urgentTooltip = urgentTooltip
+		w.icons = icons
+		w.iconMap = iconMap
+		w.maxIcons = maxIcons
+		return w, nil
+	})
 }
 
-func NewWorkspace() *Workspace {
+func NewWorkspace(output string) *Workspace {
 	return &Workspace{
-		workspaces: []hyprlandWorkspace{},
-		buttons:    make(map[string]*gtk.Button),
+		buttons:  make(map[workspaceKey]*workspaceButton),
+		provider: providers.NewWorkspaces(),
+		output:   output,
+		maxIcons: 4,
 	}
 }
 
@@ -50,143 +125,139 @@ func (w *Workspace) Create() error {
 	}
 
 	w.box = box
+	return nil
+}
 
-	// Get current monitor
-	cmd := exec.Command("hyprctl", "monitors", "-j")
-	output, err := cmd.Output()
-	if err != nil {
-		fmt.Printf("Error getting monitors: %v\n", err)
-		return err
-	}
+func (w *Workspace) Start(ctx context.Context) error {
+	w.ctx = ctx
+	w.destroyed = false
 
-	var monitors []struct {
-		Name   string `json:"name"`
-		Active bool   `json:"active"`
-		ID     int    `json:"id"`
-	}
-	if err := json.Unmarshal(output, &monitors); err != nil {
-		fmt.Printf("Error parsing monitors JSON: %v\n", err)
-		return err
+	if !w.icons {
+		return nil
 	}
 
-	// Find the active monitor
-	for _, m := range monitors {
-		if m.Active {
-			w.activeMonitor = strconv.Itoa(m.ID)
-			break
+	// Icons of desktop entries are found once they are indexed, away from
+	// the GTK thread
+	w.Go(ctx, func(ctx context.Context) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-libs.LoadDesktopIcons():
 		}
-	}
 
-	w.updateMonitors()
-
-	// Subscribe to Hyprland workspace events
-	go w.subscribeToHyprland()
-
-	go libs.ListenForHyprlandEvents(func(eventType, data string) {
-		switch eventType {
-		case "workspace":
-			w.activeWorkspace = data
-		case "focusedmon":
-			d := strings.Split(data, ",")
-			id, err := strconv.Atoi(d[1])
-			if err != nil {
-				fmt.Printf("Error converting id to int: %v\n", err)
+		glib.IdleAdd(func() {
+			if w.destroyed {
 				return
 			}
-			w.activeMonitor = d[0]
-			for _, m := range w.monitors {
-				if m.ID == id {
-					w.activeMonitor = d[0]
-					w.activeWorkspace = (string)(m.ActiveWorkspaceID)
-					break
-				}
+
+			for _, b := range w.buttons {
+				b.filled = false
 			}
-		}
-		glib.IdleAdd(func() {
-			w.Render()
+			w.render(w.snapshot)
 		})
 	})
 
 	return nil
 }
 
-func (w *Workspace) subscribeToHyprland() {
-	cmd := exec.Command("hyprctl", "workspaces", "-j")
-	output, err := cmd.Output()
-	if err != nil {
-		fmt.Printf("Error getting workspaces: %v\n", err)
-		return
-	}
-
-	// Parse workspaces and update buttons
-	workspaces := parseWorkspaces(string(output))
-	w.updateWorkspaces(workspaces)
+func (w *Workspace) Destroy() {
+	w.destroyed = true
+	w.Stop()
+	w.box.Destroy()
 }
 
-func (w *Workspace) updateMonitors() {
+func (w *Workspace) CompositorChanged(c compositor.Compositor, output string) {
+	w.compositor = c
 
-	cmd := exec.Command("hyprctl", "monitors", "-j")
-	output, err := cmd.Output()
-	if err != nil {
-		fmt.Printf("Error getting monitors: %v\n", err)
-		return
+	// Until the output of the bar is known, every workspace is shown
+	filter := w.output
+	if filter == workspaceOutputBar {
+		filter = output
 	}
 
-	var monitors []monitors
-	if err := json.Unmarshal(output, &monitors); err != nil {
-		fmt.Printf("Error parsing monitors JSON: %v\n", err)
-		return
-	}
-
-	w.monitors = monitors
-
+	w.render(w.provider.Snapshot(c.State(), filter, w.persistent))
 }
 
-func (w *Workspace) updateWorkspaces(workspaces []hyprlandWorkspace) {
-	// Create a map of existing workspace names
-	existingWorkspaces := make(map[string]bool)
-	for _, ws := range workspaces {
-		existingWorkspaces[ws.Name] = true
+func (w *Workspace) render(snapshot providers.WorkspacesSnapshot) {
+	w.snapshot = snapshot
+
+	// Create a set of existing workspaces
+	existingWorkspaces := make(map[workspaceKey]bool)
+	for _, ws := range snapshot.Workspaces {
+		existingWorkspaces[workspaceKey{ws.Monitor, ws.Name}] = true
 	}
 
 	// Remove buttons for workspaces that no longer exist
-	for name, button := range w.buttons {
-		if !existingWorkspaces[name] {
-			w.box.Remove(button)
-			delete(w.buttons, name)
+	for key, b := range w.buttons {
+		if !existingWorkspaces[key] {
+			w.box.Remove(b.button)
+			delete(w.buttons, key)
 		}
 	}
 
 	// Update or create buttons for current workspaces
-	for _, ws := range workspaces {
-		button, exists := w.buttons[ws.Name]
+	for i, ws := range snapshot.Workspaces {
+		key := workspaceKey{ws.Monitor, ws.Name}
+		b, exists := w.buttons[key]
 		if !exists {
-			var err error
 			// Create new button if it doesn't exist
-			button, err = gtk.ButtonNewWithLabel(ws.Name)
+			button, err := gtk.ButtonNew()
 			if err != nil {
-				fmt.Printf("Error creating button: %v\n", err)
+				libs.Log.Println("unable to create workspace button:", err)
 				continue
 			}
 
-			// Connect click handler
+			// The workspace is looked up on click, as it changes between
+			// renders
 			button.Connect("clicked", func() {
-				exec.Command("hyprctl", "dispatch", "workspace", ws.Name).Run()
+				for _, ws := range w.snapshot.Workspaces {
+					if (workspaceKey{ws.Monitor, ws.Name}) != key {
+						continue
+					}
+
+					if err := w.provider.Switch(w.ctx, w.compositor, ws); err != nil {
+						libs.Log.Println("unable to switch workspace:", err)
+					}
+					return
+				}
 			})
 
-			w.buttons[ws.Name] = button
+			b = &workspaceButton{button: button}
+			w.buttons[key] = b
 			w.box.PackStart(button, false, false, 0)
 		}
+		w.box.ReorderChild(b.button, i)
+
+		if err := w.fill(b, ws); err != nil {
+			libs.Log.Println("unable to fill workspace button:", err)
+		}
 
 		// Update button style based on state
-		styleContext, _ := button.GetStyleContext()
+		styleContext, _ := b.button.GetStyleContext()
 		styleContext.RemoveClass("workspace-active")
 		styleContext.RemoveClass("workspace-inactive")
 		styleContext.RemoveClass("workspace-other-display")
+		styleContext.RemoveClass("workspace-empty")
+		styleContext.RemoveClass("workspace-occupied")
+		styleContext.RemoveClass("workspace-urgent")
+
+		if ws.Windows > 0 {
+			styleContext.AddClass("workspace-occupied")
+		} else {
+			styleContext.AddClass("workspace-empty")
+		}
+
+		// Compositors drop the urgency once the workspace is visited
+		if ws.Urgent {
+			styleContext.AddClass("workspace-urgent")
+		}
+		if w.urgentTooltip {
+			b.button.SetTooltipText(urgentTooltip(ws.UrgentTitles))
+		}
 
 		if ws.IsActive {
 			styleContext.AddClass("workspace-active")
-		} else if ws.Monitor == w.activeMonitor {
+		} else if ws.Monitor == snapshot.ActiveMonitor {
 			styleContext.AddClass("workspace-inactive")
 		} else {
 			styleContext.AddClass("workspace-other-display")
@@ -196,9 +267,71 @@ func (w *Workspace) updateWorkspaces(workspaces []hyprlandWorkspace) {
 	w.box.ShowAll()
 }
 
-func (w *Workspace) Render() error {
-	// Refresh workspace list
-	w.subscribeToHyprland()
+// urgentTooltip returns the tooltip listing urgent windows, or "" for none.
+func urgentTooltip(titles []string) string {
+	if len(titles) == 0 {
+		return ""
+	}
+
+	return "Urgent: " + strings.Join(titles, "\n")
+}
+
+// fill shows the name of a workspace on its button, followed by the icons
+// of its applications if enabled. Icons that cannot be created are left
+// out; only a button without any content is an error.
+func (w *Workspace) fill(b *workspaceButton, ws providers.Workspace) error {
+	var apps []string
+	if w.icons {
+		apps = ws.Apps
+	}
+
+	if b.filled && slices.Equal(apps, b.apps) {
+		return nil
+	}
+
+	content, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 4)
+	if err != nil {
+		return fmt.Errorf("unable to create box: %w", err)
+	}
+
+	label, err := gtk.LabelNew(ws.Name)
+	if err != nil {
+		content.Destroy()
+		return fmt.Errorf("unable to create label: %w", err)
+	}
+	content.PackStart(label, false, false, 0)
+
+	for i, app := range apps {
+		if i == w.maxIcons {
+			overflow, err := gtk.LabelNew(fmt.Sprintf("+%d", len(apps)-i))
+			if err != nil {
+				libs.Log.Println("unable to create label:", err)
+				break
+			}
+			if styleContext, err := overflow.GetStyleContext(); err == nil {
+				styleContext.AddClass("workspace-overflow")
+			}
+			content.PackStart(overflow, false, false, 0)
+			break
+		}
+
+		icon, err := appIcon(app, w.iconMap)
+		if err != nil {
+			libs.Log.Printf("unable to show icon of %s: %v", app, err)
+			continue
+		}
+		content.PackStart(icon, false, false, 0)
+	}
+
+	if b.content != nil {
+		b.content.Destroy()
+	}
+	b.button.Add(content)
+	content.ShowAll()
+
+	b.content = content
+	b.apps = apps
+	b.filled = true
 	return nil
 }
 
@@ -209,13 +342,3 @@ func (w *Workspace) Name() string {
 func (w *Workspace) Box() *gtk.Box {
 	return w.box
 }
-
-func parseWorkspaces(output string) []hyprlandWorkspace {
-	var workspaces []hyprlandWorkspace
-	err := json.Unmarshal([]byte(output), &workspaces)
-	if err != nil {
-		fmt.Printf("Error parsing workspaces JSON: %v\n", err)
-		return nil
-	}
-	return workspaces
-}